@@ -0,0 +1,247 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xorcare/golden/internal/txtar"
+)
+
+// ArchiveExt the extension of the archive files of the txtar storage.
+const ArchiveExt = ".txtar"
+
+var (
+	_ Storage = new(txtarStorage)
+	_ globber = new(txtarStorage)
+)
+
+// NewTxtarStorage returns the storage in which all files of one top-level
+// test located in the root directory are stored in a single txtar archive
+// in the s storage, for example the files "testdata/TestFoo.golden" and
+// "testdata/TestFoo/sub.input" are stored in the sections "TestFoo.golden"
+// and "TestFoo/sub.input" of the archive "testdata/TestFoo.txtar". The
+// comment and the sections of other files of the archive are preserved
+// when a section is written. Files outside the root directory are
+// stored in the s storage as is.
+//
+// The txtar format requires that the data of a section ends with a
+// newline, so the single final newline of the section is not part of the
+// data, and the data containing the txtar marker lines "-- NAME --" cannot
+// be written.
+//
+// For example:
+//
+//	s := golden.NewTxtarStorage(golden.NewOSStorage(), "testdata")
+//	golden.SetTest(t).SetStorage(s).Assert(got)
+func NewTxtarStorage(s Storage, root string) Storage {
+	return &txtarStorage{s: s, root: filepath.Clean(root)}
+}
+
+type txtarStorage struct {
+	// mu serializes changes of the archives.
+	mu   sync.Mutex
+	s    Storage
+	root string
+}
+
+// locate returns the name of the archive and the name of the section in
+// which the file is stored, ok is false if the file is outside the root
+// directory.
+func (a *txtarStorage) locate(name string) (archive, section string, ok bool) {
+	rel, err := filepath.Rel(a.root, filepath.Clean(name))
+	if err != nil || rel == "." || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", false
+	}
+
+	section = filepath.ToSlash(rel)
+	top := section
+	if i := strings.IndexByte(top, '/'); i >= 0 {
+		top = top[:i]
+	}
+	if i := strings.IndexByte(top, '.'); i > 0 {
+		top = top[:i]
+	}
+
+	return filepath.Join(a.root, top+ArchiveExt), section, true
+}
+
+// load reads and parses the archive, the missing archive is empty.
+func (a *txtarStorage) load(archive string) (*txtar.Archive, error) {
+	bs, err := a.s.ReadFile(archive)
+	if os.IsNotExist(err) {
+		return new(txtar.Archive), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return txtar.Parse(bs), nil
+}
+
+// Glob returns the names of the files matching the pattern, the files of
+// the root directory are listed by the sections of the archives.
+func (a *txtarStorage) Glob(pattern string) ([]string, error) {
+	g, ok := a.s.(globber)
+	if !ok {
+		return nil, errNoGlob
+	}
+
+	names, err := g.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, name := range names {
+		if _, _, ok := a.locate(name); !ok {
+			matches = append(matches, name)
+		}
+	}
+
+	archives, err := g.Glob(filepath.Join(a.root, "*"+ArchiveExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, archive := range archives {
+		ar, err := a.load(archive)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range ar.Files {
+			name := filepath.Join(a.root, filepath.FromSlash(f.Name))
+			if ok, _ := filepath.Match(pattern, name); ok {
+				matches = append(matches, name)
+			}
+		}
+	}
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
+func (a *txtarStorage) MkdirAll(path string, perm os.FileMode) error {
+	if _, _, ok := a.locate(path); ok {
+		path = a.root
+	}
+
+	return a.s.MkdirAll(path, perm)
+}
+
+func (a *txtarStorage) ReadFile(name string) ([]byte, error) {
+	archive, section, ok := a.locate(name)
+	if !ok {
+		return a.s.ReadFile(name)
+	}
+
+	ar, err := a.load(archive)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range ar.Files {
+		if f.Name == section {
+			return bytes.TrimSuffix(f.Data, []byte("\n")), nil
+		}
+	}
+
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+func (a *txtarStorage) Remove(name string) error {
+	archive, section, ok := a.locate(name)
+	if !ok {
+		return a.s.Remove(name)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ar, err := a.load(archive)
+	if err != nil {
+		return err
+	}
+	for i, f := range ar.Files {
+		if f.Name != section {
+			continue
+		}
+
+		ar.Files = append(ar.Files[:i], ar.Files[i+1:]...)
+		if len(ar.Files) == 0 && len(ar.Comment) == 0 {
+			return a.s.Remove(archive)
+		}
+
+		fi, err := a.s.Stat(archive)
+		if err != nil {
+			return err
+		}
+
+		return a.s.WriteFile(archive, txtar.Format(ar), fi.Mode().Perm())
+	}
+
+	return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+}
+
+func (a *txtarStorage) Stat(name string) (os.FileInfo, error) {
+	archive, section, ok := a.locate(name)
+	if !ok {
+		return a.s.Stat(name)
+	}
+
+	ar, err := a.load(archive)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range ar.Files {
+		switch {
+		case f.Name == section:
+			size := len(bytes.TrimSuffix(f.Data, []byte("\n")))
+			return memoryFileInfo{name: filepath.Base(name), size: size, mode: 0644}, nil
+		case strings.HasPrefix(f.Name, section+"/"):
+			return memoryFileInfo{name: filepath.Base(name), mode: os.ModeDir | 0755}, nil
+		}
+	}
+
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+func (a *txtarStorage) WriteFile(name string, data []byte, perm os.FileMode) error {
+	archive, section, ok := a.locate(name)
+	if !ok {
+		return a.s.WriteFile(name, data, perm)
+	}
+	if txtar.HasMarker(data) {
+		return &os.PathError{Op: "write", Path: name, Err: errTxtarMarker}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ar, err := a.load(archive)
+	if err != nil {
+		return err
+	}
+
+	// The final newline is always added, so that the data that does not
+	// end with a newline is restored exactly.
+	data = append(data[:len(data):len(data)], '\n')
+	for i := range ar.Files {
+		if ar.Files[i].Name == section {
+			ar.Files[i].Data = data
+			return a.s.WriteFile(archive, txtar.Format(ar), perm)
+		}
+	}
+	ar.Files = append(ar.Files, txtar.File{Name: section, Data: data})
+
+	return a.s.WriteFile(archive, txtar.Format(ar), perm)
+}
+
+// errTxtarMarker the error of writing the data containing the marker lines.
+var errTxtarMarker = errors.New("data contains a txtar marker line")
@@ -0,0 +1,125 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTxtarStorage(t *testing.T) {
+	archive := filepath.Join("testdata", "TestFoo"+ArchiveExt)
+
+	t.Run("update-and-read", func(t *testing.T) {
+		base := NewMemoryStorage()
+		assert.NoError(t, base.MkdirAll("testdata", 0755))
+		assert.NoError(t, base.WriteFile(archive, []byte("comment\n-- TestFoo.input --\ninput\n"), 0644))
+
+		s := NewTxtarStorage(base, "testdata")
+		for _, name := range []string{"TestFoo", "TestFoo/sub", "TestFoo/sub/deep"} {
+			tool := _golden.SetTest(&bufferTB{name: name}).SetStorage(s)
+			tool.flag = &[]bool{true}[0]
+			tool.Assert([]byte("golden of " + name))
+			tool.flag = nil
+			tool.Assert([]byte("golden of " + name))
+		}
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		assert.Equal(t, "input", string(tool.SetTarget(Input).Read()))
+
+		bs, err := base.ReadFile(archive)
+		assert.NoError(t, err)
+		assert.Equal(t, "comment\n"+
+			"-- TestFoo.input --\ninput\n"+
+			"-- TestFoo.golden --\ngolden of TestFoo\n"+
+			"-- TestFoo/sub.golden --\ngolden of TestFoo/sub\n"+
+			"-- TestFoo/sub/deep.golden --\ngolden of TestFoo/sub/deep\n",
+			string(bs))
+	})
+	t.Run("trailing-newline-is-preserved", func(t *testing.T) {
+		s := NewTxtarStorage(NewMemoryStorage(), "testdata")
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		for _, data := range []string{"", "\n", "data", "data\n", "data\n\n"} {
+			name := filepath.Join("testdata", "TestFoo.golden")
+			assert.NoError(t, s.WriteFile(name, []byte(data), 0644))
+			bs, err := s.ReadFile(name)
+			assert.NoError(t, err)
+			assert.Equal(t, data, string(bs))
+		}
+	})
+	t.Run("stat", func(t *testing.T) {
+		s := NewTxtarStorage(NewMemoryStorage(), "testdata")
+		assert.NoError(t, s.MkdirAll(filepath.Join("testdata", "TestFoo"), 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo", "sub.golden"), []byte("data"), 0644))
+
+		fi, err := s.Stat(filepath.Join("testdata", "TestFoo"))
+		assert.NoError(t, err)
+		assert.True(t, fi.IsDir())
+
+		fi, err = s.Stat(filepath.Join("testdata", "TestFoo", "sub.golden"))
+		assert.NoError(t, err)
+		assert.False(t, fi.IsDir())
+		assert.Equal(t, int64(4), fi.Size())
+
+		_, err = s.Stat(filepath.Join("testdata", "TestFoo", "other.golden"))
+		assert.True(t, os.IsNotExist(err))
+	})
+	t.Run("remove", func(t *testing.T) {
+		base := NewMemoryStorage()
+		s := NewTxtarStorage(base, "testdata")
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.golden"), []byte("a"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.input"), []byte("b"), 0644))
+
+		assert.NoError(t, s.Remove(filepath.Join("testdata", "TestFoo.golden")))
+		bs, err := base.ReadFile(archive)
+		assert.NoError(t, err)
+		assert.Equal(t, "-- TestFoo.input --\nb\n", string(bs))
+
+		assert.True(t, os.IsNotExist(s.Remove(filepath.Join("testdata", "TestFoo.golden"))))
+		assert.NoError(t, s.Remove(filepath.Join("testdata", "TestFoo.input")))
+		_, err = base.Stat(archive)
+		assert.True(t, os.IsNotExist(err), "empty archive must be removed")
+	})
+	t.Run("marker-in-data", func(t *testing.T) {
+		s := NewTxtarStorage(NewMemoryStorage(), "testdata")
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		err := s.WriteFile(filepath.Join("testdata", "TestFoo.golden"), []byte("-- x --\n"), 0644)
+		assert.EqualError(t, err, "write testdata/TestFoo.golden: data contains a txtar marker line")
+	})
+	t.Run("outside-root", func(t *testing.T) {
+		base := NewMemoryStorage()
+		s := NewTxtarStorage(base, "testdata")
+		assert.NoError(t, s.WriteFile("file", []byte("data"), 0644))
+		bs, err := base.ReadFile("file")
+		assert.NoError(t, err)
+		assert.Equal(t, "data", string(bs))
+	})
+}
+
+func TestTxtarStorage_Glob(t *testing.T) {
+	base := NewMemoryStorage()
+	assert.NoError(t, base.MkdirAll("other", 0755))
+	assert.NoError(t, base.WriteFile(filepath.Join("other", "x.input"), nil, 0644))
+
+	s := NewTxtarStorage(base, "testdata")
+	assert.NoError(t, s.MkdirAll(filepath.Join("testdata", "TestFoo"), 0755))
+	for _, name := range []string{"TestFoo/b.input", "TestFoo/a.input", "TestFoo/a.golden", "TestBar/c.input"} {
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", filepath.FromSlash(name)), []byte(name), 0644))
+	}
+
+	matches, err := s.(globber).Glob(filepath.Join("testdata", "TestFoo", "*.input"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join("testdata", "TestFoo", "a.input"),
+		filepath.Join("testdata", "TestFoo", "b.input"),
+	}, matches)
+
+	matches, err = s.(globber).Glob(filepath.Join("other", "*.input"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join("other", "x.input")}, matches)
+}
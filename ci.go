@@ -0,0 +1,104 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// updateInCIEnvName the name of the environment variable that explicitly
+// allows updating the golden files in the CI environment.
+const updateInCIEnvName = "GOLDEN_UPDATE_IN_CI"
+
+// ciEnvNames the names of the environment variables by which the CI
+// environment is detected.
+var ciEnvNames = []string{
+	"CI",
+	"CONTINUOUS_INTEGRATION",
+	"BUILDKITE",
+	"CIRCLECI",
+	"CODEBUILD_BUILD_ID",
+	"DRONE",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"JENKINS_URL",
+	"TEAMCITY_VERSION",
+	"TF_BUILD",
+	"TRAVIS",
+}
+
+// updateBanner the banner printed when the golden files are updated for
+// the first time in the run.
+const updateBanner = `
+################################################################
+# golden: UPDATE MODE, the golden files are overwritten with   #
+# the actual results instead of being compared with them,      #
+# review the changes of the golden files carefully.            #
+################################################################
+`
+
+var (
+	// bannerOutput the destination of the update banner.
+	bannerOutput io.Writer = os.Stderr
+	// bannerOnce prints the update banner once per run.
+	bannerOnce sync.Once
+)
+
+// ciEnv returns the name of the environment variable by which the CI
+// environment is detected, or an empty string if it is not detected.
+func ciEnv() string {
+	for _, name := range ciEnvNames {
+		env := os.Getenv(name)
+		if env == "" {
+			continue
+		}
+		if is, err := strconv.ParseBool(env); err == nil && !is {
+			continue
+		}
+
+		return name
+	}
+
+	return ""
+}
+
+// getUpdateInCIEnv reports whether updating the golden files in the CI
+// environment is explicitly allowed.
+func getUpdateInCIEnv() bool {
+	env := os.Getenv(updateInCIEnvName)
+	if env == "" {
+		return false
+	}
+
+	is, err := strconv.ParseBool(env)
+	if err != nil {
+		const msg = "cannot parse flag %q, error: %v"
+		panic(fmt.Sprintf(msg, updateInCIEnvName, err))
+	}
+
+	return is
+}
+
+// checkUpdateAllowed fails the test if the golden files are updated in the
+// CI environment without explicit permission, so that the CI job cannot
+// pass by overwriting the golden files.
+func (t Tool) checkUpdateAllowed() {
+	if name := ciEnv(); name != "" && !getUpdateInCIEnv() {
+		const f = "golden: refusing to update the golden files in the CI environment" +
+			" detected by the %s environment variable, set %s=true to allow it"
+		t.test.Fatalf(f, name, updateInCIEnvName)
+	}
+}
+
+// printUpdateBanner prints the update banner once per run.
+func printUpdateBanner() {
+	bannerOnce.Do(func() {
+		_, _ = fmt.Fprint(bannerOutput, updateBanner)
+	})
+}
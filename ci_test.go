@@ -0,0 +1,101 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// helperUnsetCIEnv clears the environment variables of the CI environment
+// detection and restores them at the end of the test.
+func helperUnsetCIEnv(t *testing.T) {
+	for _, name := range append(ciEnvNames, updateInCIEnvName) {
+		if value, ok := os.LookupEnv(name); ok {
+			name := name
+			t.Cleanup(func() { _ = os.Setenv(name, value) })
+		} else {
+			name := name
+			t.Cleanup(func() { _ = os.Unsetenv(name) })
+		}
+		assert.NoError(t, os.Unsetenv(name))
+	}
+}
+
+func Test_ciEnv(t *testing.T) {
+	helperUnsetCIEnv(t)
+	assert.Equal(t, "", ciEnv())
+
+	assert.NoError(t, os.Setenv("CI", "false"))
+	assert.Equal(t, "", ciEnv())
+
+	assert.NoError(t, os.Setenv("CI", "true"))
+	assert.Equal(t, "CI", ciEnv())
+
+	assert.NoError(t, os.Unsetenv("CI"))
+	assert.NoError(t, os.Setenv("JENKINS_URL", "https://jenkins.example.com"))
+	assert.Equal(t, "JENKINS_URL", ciEnv())
+}
+
+func TestTool_checkUpdateAllowed(t *testing.T) {
+	newTool := func(t *testing.T) (Tool, *bufferTB) {
+		tb := &bufferTB{name: t.Name()}
+		tool := _golden.SetTest(tb).SetStorage(NewMemoryStorage())
+		tool.flag = &[]bool{true}[0]
+		return tool, tb
+	}
+
+	t.Run("refused-in-ci", func(t *testing.T) {
+		helperUnsetCIEnv(t)
+		assert.NoError(t, os.Setenv("GITHUB_ACTIONS", "true"))
+
+		tool, tb := newTool(t)
+		assert.Panics(t, func() { tool.Update([]byte("golden")) })
+		assert.Contains(t, tb.String(), "golden: refusing to update the golden files in the CI"+
+			" environment detected by the GITHUB_ACTIONS environment variable,"+
+			" set GOLDEN_UPDATE_IN_CI=true to allow it")
+	})
+	t.Run("allowed-in-ci", func(t *testing.T) {
+		helperUnsetCIEnv(t)
+		assert.NoError(t, os.Setenv("CI", "true"))
+		assert.NoError(t, os.Setenv(updateInCIEnvName, "true"))
+
+		tool, _ := newTool(t)
+		assert.NotPanics(t, func() { tool.Update([]byte("golden")) })
+	})
+	t.Run("allowed-outside-ci", func(t *testing.T) {
+		helperUnsetCIEnv(t)
+
+		tool, _ := newTool(t)
+		assert.NotPanics(t, func() { tool.Update([]byte("golden")) })
+	})
+	t.Run("parsing-error", func(t *testing.T) {
+		helperUnsetCIEnv(t)
+		assert.NoError(t, os.Setenv("CI", "true"))
+		assert.NoError(t, os.Setenv(updateInCIEnvName, "folse"))
+
+		tool, _ := newTool(t)
+		assert.PanicsWithValue(t, "cannot parse flag \"GOLDEN_UPDATE_IN_CI\", error:"+
+			" strconv.ParseBool: parsing \"folse\": invalid syntax",
+			func() { tool.Update([]byte("golden")) })
+	})
+}
+
+func Test_printUpdateBanner(t *testing.T) {
+	origin := bannerOutput
+	defer func() { bannerOutput = origin }()
+
+	buf := new(bytes.Buffer)
+	bannerOutput = buf
+	bannerOnce = sync.Once{}
+
+	printUpdateBanner()
+	printUpdateBanner()
+	assert.Equal(t, updateBanner, buf.String(), "banner must be printed once")
+}
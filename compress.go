@@ -0,0 +1,168 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// GzipExt the extension added to the names of the compressed files of the
+// gzip storage.
+const GzipExt = ".gz"
+
+var (
+	_ Storage = gzipStorage{}
+	_ globber = gzipStorage{}
+)
+
+// NewGzipStorage returns the storage that transparently compresses files
+// whose size is above the threshold in bytes, for example the file
+// "testdata/TestFoo.golden" is stored as "testdata/TestFoo.golden.gz" in
+// the s storage. Files are decompressed on reading, so diffs are computed
+// on the decompressed content. When the file is written, its copy in the
+// other form is removed, so that there is only one copy of the file.
+//
+// The compressed files do not contain the modification time and the name,
+// so the same data is always compressed to the same bytes.
+func NewGzipStorage(s Storage, threshold int) Storage {
+	return gzipStorage{s: s, threshold: threshold}
+}
+
+type gzipStorage struct {
+	s         Storage
+	threshold int
+}
+
+// Glob returns the names of the files matching the pattern, the compressed
+// files are listed by the names without the extension.
+func (g gzipStorage) Glob(pattern string) ([]string, error) {
+	gs, ok := g.s.(globber)
+	if !ok {
+		return nil, errNoGlob
+	}
+
+	plain, err := gs.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	compressed, err := gs.Glob(pattern + GzipExt)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(plain)+len(compressed))
+	matches := make([]string, 0, len(plain)+len(compressed))
+	for _, name := range plain {
+		seen[name] = true
+		matches = append(matches, name)
+	}
+	for _, name := range compressed {
+		if name = strings.TrimSuffix(name, GzipExt); !seen[name] {
+			seen[name] = true
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
+func (g gzipStorage) MkdirAll(path string, perm os.FileMode) error {
+	return g.s.MkdirAll(path, perm)
+}
+
+func (g gzipStorage) ReadFile(name string) ([]byte, error) {
+	bs, err := g.s.ReadFile(name)
+	if !os.IsNotExist(err) {
+		return bs, err
+	}
+
+	gz, gzErr := g.s.ReadFile(name + GzipExt)
+	if os.IsNotExist(gzErr) {
+		return nil, err
+	} else if gzErr != nil {
+		return nil, gzErr
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, &os.PathError{Op: "read", Path: name + GzipExt, Err: err}
+	}
+	defer r.Close()
+
+	bs, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, &os.PathError{Op: "read", Path: name + GzipExt, Err: err}
+	}
+
+	return bs, nil
+}
+
+func (g gzipStorage) Remove(name string) error {
+	err := g.s.Remove(name)
+	gzErr := g.s.Remove(name + GzipExt)
+	switch {
+	case err == nil && os.IsNotExist(gzErr):
+		return nil
+	case os.IsNotExist(err) && gzErr == nil:
+		return nil
+	case err != nil:
+		return err
+	default:
+		return gzErr
+	}
+}
+
+func (g gzipStorage) Stat(name string) (os.FileInfo, error) {
+	fi, err := g.s.Stat(name)
+	if !os.IsNotExist(err) {
+		return fi, err
+	}
+
+	fi, gzErr := g.s.Stat(name + GzipExt)
+	if os.IsNotExist(gzErr) {
+		return nil, err
+	}
+
+	return fi, gzErr
+}
+
+func (g gzipStorage) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if len(data) <= g.threshold {
+		return g.replace(name, name+GzipExt, data, perm)
+	}
+
+	buf := new(bytes.Buffer)
+	w, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return g.replace(name+GzipExt, name, buf.Bytes(), perm)
+}
+
+// replace writes the data to the file and removes its copy in the other
+// form.
+func (g gzipStorage) replace(name, other string, data []byte, perm os.FileMode) error {
+	if err := g.s.WriteFile(name, data, perm); err != nil {
+		return err
+	}
+	if err := g.s.Remove(other); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGzipStorage(t *testing.T) {
+	name := filepath.Join("testdata", "TestFoo.golden")
+	large := []byte(strings.Repeat("golden ", 100))
+
+	t.Run("compress-above-threshold", func(t *testing.T) {
+		base := NewMemoryStorage()
+		s := NewGzipStorage(base, 64)
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+
+		assert.NoError(t, s.WriteFile(name, large, 0644))
+		_, err := base.Stat(name)
+		assert.True(t, os.IsNotExist(err))
+		gz, err := base.ReadFile(name + GzipExt)
+		assert.NoError(t, err)
+		assert.True(t, len(gz) < len(large))
+
+		bs, err := s.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, large, bs)
+
+		fi, err := s.Stat(name)
+		assert.NoError(t, err)
+		assert.False(t, fi.IsDir())
+	})
+	t.Run("deterministic", func(t *testing.T) {
+		base := NewMemoryStorage()
+		s := NewGzipStorage(base, 64)
+		assert.NoError(t, s.WriteFile("first", large, 0644))
+		assert.NoError(t, s.WriteFile("second", large, 0644))
+
+		first, err := base.ReadFile("first" + GzipExt)
+		assert.NoError(t, err)
+		second, err := base.ReadFile("second" + GzipExt)
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+	})
+	t.Run("switch-form", func(t *testing.T) {
+		base := NewMemoryStorage()
+		s := NewGzipStorage(base, 64)
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(name, large, 0644))
+		assert.NoError(t, s.WriteFile(name, []byte("golden"), 0644))
+
+		_, err := base.Stat(name + GzipExt)
+		assert.True(t, os.IsNotExist(err), "the compressed copy must be removed")
+		bs, err := s.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "golden", string(bs))
+	})
+	t.Run("remove", func(t *testing.T) {
+		s := NewGzipStorage(NewMemoryStorage(), 64)
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(name, large, 0644))
+		assert.NoError(t, s.Remove(name))
+		assert.True(t, os.IsNotExist(s.Remove(name)))
+		_, err := s.ReadFile(name)
+		assert.True(t, os.IsNotExist(err))
+	})
+	t.Run("tool", func(t *testing.T) {
+		tool := _golden.SetTest(&bufferTB{name: t.Name()}).SetStorage(NewGzipStorage(NewMemoryStorage(), 64))
+		tool.flag = &[]bool{true}[0]
+		tool.Assert(large)
+		tool.flag = nil
+		assert.False(t, tool.Equal(large).Failed())
+		assert.True(t, tool.Equal([]byte("golden")).Failed())
+	})
+}
+
+func TestGzipStorage_Glob(t *testing.T) {
+	s := NewGzipStorage(NewMemoryStorage(), 64)
+	assert.NoError(t, s.MkdirAll("testdata", 0755))
+	assert.NoError(t, s.WriteFile(filepath.Join("testdata", "small.input"), []byte("small"), 0644))
+	assert.NoError(t, s.WriteFile(filepath.Join("testdata", "large.input"), []byte(strings.Repeat("large ", 100)), 0644))
+
+	matches, err := s.(globber).Glob(filepath.Join("testdata", "*.input"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join("testdata", "large.input"),
+		filepath.Join("testdata", "small.input"),
+	}, matches)
+
+	_, err = NewGzipStorage(struct{ Storage }{NewMemoryStorage()}, 0).(globber).Glob("*")
+	assert.Equal(t, errNoGlob, err)
+}
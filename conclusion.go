@@ -56,3 +56,16 @@ func (c conclusion) FailNow() {
 		c.t.FailNow()
 	}
 }
+
+// assertAll prints the messages of all failed conclusions and then stops
+// the execution of the test if any of them has failed.
+func assertAll(t TestingTB, cs ...Conclusion) {
+	failed := false
+	for _, c := range cs {
+		c.Fail()
+		failed = failed || c.Failed()
+	}
+	if failed {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// RunDir is a functional that runs a subtest for each input file in the
+// directory. See Tool.RunDir.
+func RunDir(t *testing.T, dir string, do func(t *testing.T, input []byte) (got []byte, err error)) {
+	t.Helper()
+	_golden.SetTest(t).RunDir(dir, do)
+}
+
+// RunInputs is a functional that automates the process of reading several
+// input files of the test, the execution of the input function of testing
+// and checking the results. See Tool.RunInputs.
+func RunInputs(t TestingTB, do func(inputs map[string][]byte) (got []byte, err error)) {
+	if h, ok := t.(testingHelper); ok {
+		h.Helper()
+	}
+	SetTest(t).RunInputs(do)
+}
+
+// RunInputs is a functional that automates the process of reading several
+// input files of the test, the execution of the input function of testing
+// and checking the results. The input files are the files of the
+// directory of the test in the configured layout, for example
+// "testdata/TestFoo", named `<name>.input` or `<name>.input.<ext>`, they
+// are passed to the do function by the names without the ".input"
+// element, for example the files "testdata/TestFoo/schema.input.sql" and
+// "testdata/TestFoo/data.input.csv" are passed by the names "schema.sql"
+// and "data.csv". The result and the error are checked as in Run, for
+// example the result is compared with "testdata/TestFoo.golden". The
+// input files of the subtests of the test in the default layout, like
+// "testdata/TestFoo/sub.input", also match, so the function is not
+// intended for tests with subtests.
+func (t Tool) RunInputs(do func(inputs map[string][]byte) (got []byte, err error)) {
+	dir := t.inputsDir()
+	pattern := filepath.Join(dir, "*."+Input.String()+"*")
+
+	inputs := make(map[string][]byte)
+	for _, file := range t.inputFiles(pattern) {
+		key, ok := inputKey(filepath.Base(file))
+		if !ok {
+			continue
+		}
+
+		bs, err := t.readFile(file)
+		t.noError(err)
+		inputs[key] = bs
+	}
+	if len(inputs) == 0 {
+		t.test.Fatalf("golden: no input files matching the pattern: %s", pattern)
+	}
+
+	t.assertResult(do(inputs))
+}
+
+// inputsDir returns the directory of the input files of the test in the
+// configured layout, it is the directory of the files of the subtests.
+func (t Tool) inputsDir() string {
+	name := t.test.Name()
+	if t.file != "" {
+		name = t.file
+	}
+
+	// The name is escaped by the layout, so the unescaped name is used.
+	tool := t.SetTarget(Input)
+	tool.file = name + "/" + Input.String()
+	return filepath.Dir(tool.fullPath())
+}
+
+// inputKey returns the name of the input file without the ".input" element,
+// ok is false if the name is not `<name>.input` or `<name>.input.<ext>`.
+// The compressed files are read by the names without the extension, so
+// the files `<name>.input.gz` are not the input files.
+func inputKey(name string) (key string, ok bool) {
+	ext := "." + Input.String()
+	if strings.HasSuffix(name, ext) {
+		return strings.TrimSuffix(name, ext), len(name) > len(ext)
+	}
+
+	i := strings.LastIndex(name, ext+".")
+	if i <= 0 {
+		return "", false
+	}
+	rest := name[i+len(ext):]
+	if strings.Contains(rest[1:], ".") || rest == "." || rest == GzipExt {
+		return "", false
+	}
+
+	return name[:i] + rest, true
+}
+
+// RunDir is a functional that runs a subtest for each input file in the
+// directory, the "drop a file in, get a test" pattern. For each file
+// matching `<dir>/*.input` the subtest named after the file is run, the
+// data of the file is passed to the do function and the result is checked
+// as in Run against the golden file of the same name located by the
+// layout, for example the result for the input file
+// "testdata/parser/expr.input" is compared with "testdata/parser/expr.golden".
+// The files are listed in the storage of the tool, so the storage must
+// support listing, see SetStorage. The test of the tool must support
+// subtests like *testing.T.
+func (t Tool) RunDir(dir string, do func(t *testing.T, input []byte) (got []byte, err error)) {
+	if h, ok := t.test.(testingHelper); ok {
+		h.Helper()
+	}
+
+	ext := "." + Input.String()
+	files := t.inputFiles(filepath.Join(dir, "*"+ext))
+	runner, ok := t.test.(testingRunner)
+	if !ok {
+		t.test.Fatalf("golden: the test %s does not support subtests required by RunDir", t.test.Name())
+	}
+
+	for _, file := range files {
+		file, name := file, strings.TrimSuffix(filepath.Base(file), ext)
+		runner.Run(name, func(tb *testing.T) {
+			tool := t.SetTest(tb)
+			tool.dir, tool.file = dir, name
+			if tool.portability == PortabilityEscape {
+				// The name of the file is already escaped.
+				tool.file = UnescapeName(name)
+			}
+
+			input, err := tool.readFile(file)
+			tool.noError(err)
+			tool.assertResult(do(tb, input))
+		})
+	}
+}
+
+// testingRunner the interface of the test that can run subtests.
+type testingRunner interface {
+	Run(name string, f func(t *testing.T)) bool
+}
+
+// inputFiles returns the names of the files matching the pattern, the test
+// fails if there are no such files.
+func (t Tool) inputFiles(pattern string) []string {
+	if t.glob == nil {
+		t.test.Fatalf("%s", errNoGlob)
+	}
+
+	files, err := t.glob(pattern)
+	t.noError(err)
+	if len(files) == 0 {
+		t.test.Fatalf("golden: no input files matching the pattern: %s", pattern)
+	}
+
+	return files
+}
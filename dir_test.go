@@ -0,0 +1,215 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTool_RunDir(t *testing.T) {
+	dir := filepath.Join("testdata", "parser")
+
+	t.Run("input-files", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll(dir, 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "expr.input"), []byte("expr"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "expr.golden"), []byte("EXPR"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "stmt.input"), []byte("stmt"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "stmt.golden"), []byte("STMT"), 0644))
+
+		var inputs []string
+		tool := _golden.SetTest(t).SetStorage(s)
+		tool.RunDir(dir, func(t *testing.T, input []byte) ([]byte, error) {
+			inputs = append(inputs, t.Name()+":"+string(input))
+			return bytes.ToUpper(input), nil
+		})
+		assert.Equal(t, []string{
+			"TestTool_RunDir/input-files/expr:expr",
+			"TestTool_RunDir/input-files/stmt:stmt",
+		}, inputs)
+	})
+	t.Run("wrapped-storage", func(t *testing.T) {
+		s := NewTxtarStorage(NewGzipStorage(NewMemoryStorage(), 0), "testdata")
+		assert.NoError(t, s.MkdirAll(dir, 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "expr.input"), []byte("expr"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "expr.golden"), []byte("EXPR"), 0644))
+
+		var inputs []string
+		tool := _golden.SetTest(t).SetStorage(s)
+		tool.RunDir(dir, func(t *testing.T, input []byte) ([]byte, error) {
+			inputs = append(inputs, string(input))
+			return bytes.ToUpper(input), nil
+		})
+		assert.Equal(t, []string{"expr"}, inputs)
+	})
+	t.Run("extension-last-layout", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll(dir, 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "expr.input"), []byte("expr"), 0644))
+
+		var inputs []string
+		tool := _golden.SetTest(t).SetStorage(s).SetPathTemplate(ExtensionLastPathTemplate).setExtension("json")
+		tool.flag = &[]bool{true}[0]
+		tool.RunDir(dir, func(t *testing.T, input []byte) ([]byte, error) {
+			inputs = append(inputs, string(input))
+			return bytes.ToUpper(input), nil
+		})
+		assert.Equal(t, []string{"expr"}, inputs)
+
+		bs, err := s.ReadFile(filepath.Join(dir, "expr.golden.json"))
+		assert.NoError(t, err)
+		assert.Equal(t, "EXPR", string(bs))
+	})
+	t.Run("escaped-names", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll(dir, 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "a%3Ab.input"), []byte("ab"), 0644))
+
+		tool := _golden.SetTest(t).SetStorage(s).SetPortability(PortabilityEscape)
+		tool.flag = &[]bool{true}[0]
+		tool.RunDir(dir, func(t *testing.T, input []byte) ([]byte, error) {
+			return bytes.ToUpper(input), nil
+		})
+
+		bs, err := s.ReadFile(filepath.Join(dir, "a%3Ab.golden"))
+		assert.NoError(t, err)
+		assert.Equal(t, "AB", string(bs))
+	})
+	t.Run("no-subtests", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll(dir, 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "expr.input"), []byte("expr"), 0644))
+
+		tb := &bufferTB{name: "TestFoo"}
+		tool := _golden.SetTest(tb).SetStorage(s)
+		assert.Panics(t, func() {
+			tool.RunDir(dir, func(*testing.T, []byte) ([]byte, error) { return nil, nil })
+		})
+		assert.Contains(t, tb.String(), "golden: the test TestFoo does not support subtests required by RunDir")
+	})
+	t.Run("no-input-files", func(t *testing.T) {
+		tb := &bufferTB{name: t.Name()}
+		tool := _golden.SetTest(tb).SetStorage(NewMemoryStorage())
+		assert.Panics(t, func() {
+			tool.RunDir(dir, func(*testing.T, []byte) ([]byte, error) { return nil, nil })
+		})
+		assert.Contains(t, tb.String(), "golden: no input files matching the pattern: "+
+			filepath.Join(dir, "*.input"))
+	})
+	t.Run("listing-not-supported", func(t *testing.T) {
+		tb := &bufferTB{name: t.Name()}
+		tool := _golden.SetTest(tb).SetStorage(struct{ Storage }{NewMemoryStorage()})
+		assert.Panics(t, func() {
+			tool.RunDir(dir, func(*testing.T, []byte) ([]byte, error) { return nil, nil })
+		})
+		assert.Contains(t, tb.String(), errNoGlob.Error())
+	})
+}
+
+func TestTool_RunInputs(t *testing.T) {
+	dir := filepath.Join("testdata", "TestFoo")
+
+	t.Run("inputs", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll(dir, 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "schema.input.sql"), []byte("create table t;"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "data.input.csv"), []byte("1,2"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "plain.input"), []byte("plain"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "notes.inputs.txt"), []byte("notes"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "packed.input.gz"), []byte("gzip"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "other.golden"), []byte("other"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.golden"), []byte("3"), 0644))
+
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		tool.RunInputs(func(inputs map[string][]byte) ([]byte, error) {
+			assert.Equal(t, map[string][]byte{
+				"schema.sql": []byte("create table t;"),
+				"data.csv":   []byte("1,2"),
+				"plain":      []byte("plain"),
+			}, inputs)
+			return []byte("3"), nil
+		})
+	})
+	t.Run("path-template", func(t *testing.T) {
+		dir := filepath.Join("testdata", "input", "TestFoo")
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll(dir, 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "a.input"), []byte("a"), 0644))
+
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s).
+			SetPathTemplate("{{.Dir}}/{{.Target}}/{{.Test}}{{.Prefix}}{{.Ext}}")
+		assert.Equal(t, dir, tool.inputsDir())
+		tool.flag = &[]bool{true}[0]
+		tool.RunInputs(func(inputs map[string][]byte) ([]byte, error) {
+			assert.Equal(t, map[string][]byte{"a": []byte("a")}, inputs)
+			return []byte("1"), nil
+		})
+	})
+	t.Run("escaped-names", func(t *testing.T) {
+		dir := filepath.Join("testdata", "TestFoo", "a%3Ab")
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll(dir, 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "x.input"), []byte("x"), 0644))
+
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo/a:b"}).SetStorage(s).SetPortability(PortabilityEscape)
+		assert.Equal(t, filepath.Join("testdata", "TestFoo", "a%3Ab.input"), tool.SetTarget(Input).path())
+		assert.Equal(t, dir, tool.inputsDir())
+		tool.flag = &[]bool{true}[0]
+		tool.RunInputs(func(inputs map[string][]byte) ([]byte, error) {
+			assert.Equal(t, map[string][]byte{"x": []byte("x")}, inputs)
+			return []byte("1"), nil
+		})
+	})
+	t.Run("error-golden", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll(dir, 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join(dir, "a.input"), []byte("a"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.err.golden"), []byte("failed"), 0644))
+
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s).SetErrorGolden(true)
+		assert.NotPanics(t, func() {
+			tool.RunInputs(func(map[string][]byte) ([]byte, error) { return nil, errors.New("failed") })
+		})
+	})
+	t.Run("no-inputs", func(t *testing.T) {
+		tb := &bufferTB{name: "TestFoo"}
+		tool := _golden.SetTest(tb).SetStorage(NewMemoryStorage())
+		assert.Panics(t, func() {
+			tool.RunInputs(func(map[string][]byte) ([]byte, error) { return nil, nil })
+		})
+		assert.Contains(t, tb.String(), "golden: no input files matching the pattern: "+
+			filepath.Join(dir, "*.input*"))
+	})
+}
+
+func Test_inputKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		ok   bool
+	}{
+		{name: "plain.input", key: "plain", ok: true},
+		{name: "schema.input.sql", key: "schema.sql", ok: true},
+		{name: "a.b.input.sql", key: "a.b.sql", ok: true},
+		{name: "notes.inputs.txt"},
+		{name: "data.input.tar.gz"},
+		{name: "packed.input.gz"},
+		{name: "trailing.input."},
+		{name: ".input"},
+		{name: "x.golden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := inputKey(tt.name)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.key, key)
+		})
+	}
+}
@@ -26,6 +26,10 @@ using the following command:
 
 	go test ./... -update
 
+Updating the golden files is refused in the CI environment detected by the
+environment variables like `CI=true`, so that a job accidentally running in
+the update mode cannot pass, set `GOLDEN_UPDATE_IN_CI=true` to allow it.
+
 Golden files are placed in directory `testdata` this directory is ignored by
 the standard tools go, and it can accommodate a variety of data used in test or
 samples.
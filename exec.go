@@ -0,0 +1,109 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Exec runs the command and compares its standard output, standard error
+// and exit code with the golden files. See Tool.Exec.
+func Exec(t TestingTB, cmd *exec.Cmd) {
+	if h, ok := t.(testingHelper); ok {
+		h.Helper()
+	}
+	SetTest(t).Exec(cmd)
+}
+
+// Exec runs the command and compares its standard output, standard error
+// and exit code with their own golden files, for example
+// "testdata/TestFoo.stdout.golden", "testdata/TestFoo.stderr.golden" and
+// "testdata/TestFoo.code.golden". An empty output is compared with
+// a missing golden file, so it is not stored. The working directory of the
+// command and the temporary directory are replaced in the outputs by $WORK
+// and $TMPDIR, so the golden files do not depend on the machine. The
+// standard output and standard error of the command must not be set.
+func (t Tool) Exec(cmd *exec.Cmd) {
+	if h, ok := t.test.(testingHelper); ok {
+		h.Helper()
+	}
+
+	if cmd.Stdout != nil || cmd.Stderr != nil {
+		t.test.Fatalf("golden: the standard output and error of the command %s must not be set", cmd)
+	}
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	t.test.Logf("golden: running the command: %s", cmd)
+	code := 0
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); errors.As(err, &exitErr) {
+		code = exitErr.ExitCode()
+	} else {
+		t.noError(err)
+	}
+
+	scrub := newScrubber(cmd.Dir)
+	assertAll(t.test,
+		t.output("stdout").Equal(scrub(stdout.Bytes())),
+		t.output("stderr").Equal(scrub(stderr.Bytes())),
+		t.output("code").Equal([]byte(strconv.Itoa(code)+"\n")),
+	)
+}
+
+// newScrubber returns the function that replaces the working directory of
+// the command and the temporary directory by $WORK and $TMPDIR, the empty
+// output is replaced by nil.
+func newScrubber(dir string) func([]byte) []byte {
+	names := map[string]string{}
+	add := func(path, name string) {
+		if path == "" {
+			return
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		names[path] = name
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			names[real] = name
+		}
+	}
+	add(os.TempDir(), "$TMPDIR")
+	add(dir, "$WORK")
+
+	// The longest paths are replaced first, because the working directory
+	// is usually inside the temporary directory.
+	paths := make([]string, 0, len(names))
+	for path := range names {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) > len(paths[j])
+		}
+		return paths[i] < paths[j]
+	})
+
+	oldnew := make([]string, 0, 2*len(paths))
+	for _, path := range paths {
+		oldnew = append(oldnew, path, names[path])
+	}
+	replacer := strings.NewReplacer(oldnew...)
+
+	return func(bs []byte) []byte {
+		if len(bs) == 0 {
+			return nil
+		}
+		return []byte(replacer.Replace(string(bs)))
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExecHelperProcess is not a real test, it is the process run by the
+// tests of Exec.
+func TestExecHelperProcess(t *testing.T) {
+	if os.Getenv("GOLDEN_EXEC_HELPER_PROCESS") != "1" {
+		t.Skip("it is the helper process of the tests of Exec")
+	}
+	wd, _ := os.Getwd()
+	fmt.Fprintf(os.Stdout, "work: %s\n", wd)
+	if bs, err := os.ReadFile("input.txt"); err == nil {
+		os.Stdout.Write(bs)
+	}
+	fmt.Fprintf(os.Stderr, "temp: %s\n", filepath.Join(os.TempDir(), "x"))
+	os.Exit(3)
+}
+
+func helperCommand(t *testing.T) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestExecHelperProcess$")
+	cmd.Env = append(os.Environ(), "GOLDEN_EXEC_HELPER_PROCESS=1")
+	cmd.Dir = t.TempDir()
+	return cmd
+}
+
+func TestTool_Exec(t *testing.T) {
+	t.Run("update", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		tool.flag = &[]bool{true}[0]
+		tool.Exec(helperCommand(t))
+
+		for name, want := range map[string]string{
+			"TestFoo.stdout.golden": "work: $WORK\n",
+			"TestFoo.stderr.golden": "temp: $TMPDIR" + string(filepath.Separator) + "x\n",
+			"TestFoo.code.golden":   "3\n",
+		} {
+			bs, err := s.ReadFile(filepath.Join("testdata", name))
+			assert.NoError(t, err, name)
+			assert.Equal(t, want, string(bs), name)
+		}
+
+		tool = tool.SetTest(&bufferTB{name: "TestFoo"})
+		tool.flag = nil
+		assert.NotPanics(t, func() { tool.Exec(helperCommand(t)) })
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.code.golden"), []byte("0\n"), 0644))
+
+		tb := &bufferTB{name: "TestFoo"}
+		tool := _golden.SetTest(tb).SetStorage(s).SetMissing(MissingNil)
+		assert.Panics(t, func() { tool.Exec(helperCommand(t)) })
+		assert.Contains(t, tb.String(), "+3")
+	})
+	t.Run("output-set", func(t *testing.T) {
+		tb := &bufferTB{name: "TestFoo"}
+		cmd := helperCommand(t)
+		cmd.Stdout = os.Stdout
+		assert.Panics(t, func() { _golden.SetTest(tb).Exec(cmd) })
+		assert.Contains(t, tb.String(), "must not be set")
+	})
+	t.Run("not-found", func(t *testing.T) {
+		tb := &bufferTB{name: "TestFoo"}
+		cmd := exec.Command(filepath.Join(t.TempDir(), "not-found"))
+		assert.Panics(t, func() { _golden.SetTest(tb).Exec(cmd) })
+	})
+}
+
+func Test_newScrubber(t *testing.T) {
+	work := filepath.Join(os.TempDir(), "work")
+	scrub := newScrubber(work)
+
+	assert.Nil(t, scrub(nil))
+	assert.Nil(t, scrub([]byte{}))
+	assert.Equal(t, "$WORK/a $TMPDIR/b $WORK", string(scrub([]byte(
+		work+"/a "+os.TempDir()+"/b "+work,
+	))))
+}
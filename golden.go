@@ -8,11 +8,13 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"text/template"
 	"unicode"
 
 	"github.com/stretchr/testify/assert"
@@ -42,10 +44,30 @@ type Tool struct {
 	dir       string
 	fileMode  os.FileMode
 	modeDir   os.FileMode
-	target    target
+	target    Target
 	flag      *bool
 	prefix    string
 	extension string
+	// sum it stores the name of the checksum manifest file, if it is
+	// empty, then the manifest is not used.
+	sum string
+	// missing it stores how a missing golden file is handled.
+	missing Missing
+	// portability it stores how names that cannot be created on all
+	// operating systems are handled.
+	portability Portability
+	// template it stores the parsed path template, if it is nil, then
+	// the default path layout is used.
+	template *template.Template
+	// fallback it stores the parsed path template of the layout from which
+	// the data is read if the file is not found by the template.
+	fallback *template.Template
+	// file it stores the name of the file used in the path instead of the
+	// name of the test, if it is empty, then the name of the test is used.
+	file string
+	// errGolden it stores whether Run compares the error of the function
+	// with the error golden file instead of failing the test.
+	errGolden bool
 	// want it stores manually set expected data, if it is nil, then the
 	// data will be read from the files, otherwise the value from this
 	// field will be taken.
@@ -56,6 +78,13 @@ type Tool struct {
 	remove    func(name string) error
 	stat      func(name string) (os.FileInfo, error)
 	writeFile func(filename string, data []byte, perm os.FileMode) error
+	// glob it lists the files matching the pattern, if it is nil, then the
+	// storage does not support listing.
+	glob func(pattern string) ([]string, error)
+	// readFS it is the flag that the data is read from the file system set
+	// by SetFS, which does not see the files updated in the file system of
+	// the operating system.
+	readFS bool
 }
 
 // tool object with predefined parameters intended for use in global
@@ -74,8 +103,19 @@ var _golden = Tool{
 	readFile:  ioutil.ReadFile,
 	remove:    os.Remove,
 	stat:      os.Stat,
-	writeFile: ioutil.WriteFile,
-}
+	writeFile: writeFileAtomic,
+	glob:      filepath.Glob,
+}
+
+const (
+	// DefaultPathTemplate the path template of the default layout, for
+	// example "testdata/TestFoo.json.golden".
+	DefaultPathTemplate = "{{.Dir}}/{{.Test}}{{.Prefix}}{{.Ext}}.{{.Target}}"
+	// ExtensionLastPathTemplate the path template of the layout in which
+	// the real file extension is placed last for editor support, for
+	// example "testdata/TestFoo.golden.json".
+	ExtensionLastPathTemplate = "{{.Dir}}/{{.Test}}{{.Prefix}}.{{.Target}}{{.Ext}}"
+)
 
 const updateEnvName = "GOLDEN_UPDATE"
 
@@ -160,23 +200,49 @@ func (t Tool) Assert(got []byte) {
 // the value from the golden file. Also, built-in functionality for
 // updating golden files using the command line flag.
 func (t Tool) Equal(got []byte) Conclusion {
-	t.Update(got)
 	if h, ok := t.test.(testingHelper); ok {
 		h.Helper()
 	}
 
-	want := t.SetTarget(Golden).Read()
+	return t.compare(func() []byte { return got }, func(i *interceptor, want []byte) bool {
+		if want == nil {
+			want = []byte(fmt.Sprintf("%#v", want))
+		}
+		if got == nil {
+			got = []byte(fmt.Sprintf("%#v", got))
+		}
+
+		return assert.Equal(i, string(want), string(got))
+	})
+}
 
-	if want == nil {
-		want = []byte(fmt.Sprintf("%#v", want))
+// compare updates the golden file with the data if the golden files are
+// updated and compares the data of the golden file with the actual data by
+// the cmp function. The missing golden file is handled according to the
+// mode, unless the data is nil, which matches the missing file. After the
+// update the file system set by SetFS is not read back, since it holds the
+// stale copy, and the data is compared with itself.
+func (t Tool) compare(data func() []byte, cmp func(i *interceptor, want []byte) bool) conclusion {
+	updated := t.update(data)
+
+	golden := t.expected()
+	var want []byte
+	found := true
+	if updated && t.readFS {
+		want = data()
+	} else {
+		want, found = golden.read()
 	}
-	if got == nil {
-		got = []byte(fmt.Sprintf("%#v", got))
+	if !found && golden.missingMode() != MissingNil && data() != nil {
+		if c, ok := golden.handleMissing(data); ok {
+			return c
+		}
+		want = data()
 	}
 
 	i := new(interceptor)
 	c := newConclusion(t.test)
-	c.successful = assert.Equal(i, string(want), string(got))
+	c.successful = cmp(i, want)
 	c.diff = i
 
 	return c
@@ -194,16 +260,10 @@ func (t Tool) JSONEq(got string) Conclusion {
 }
 
 func (t Tool) jsonEqual(got string) conclusion {
-	t.setExtension("json").update(func() []byte {
-		return []byte(jsonFormatter(t.test, got))
+	data := func() []byte { return []byte(jsonFormatter(t.test, got)) }
+	return t.setExtension("json").compare(data, func(i *interceptor, want []byte) bool {
+		return assert.JSONEq(i, string(want), got)
 	})
-
-	want := t.setExtension("json").SetTarget(Golden).Read()
-	i := new(interceptor)
-	c := newConclusion(t.test)
-	c.successful = assert.JSONEq(i, string(want), string(got))
-	c.diff = i
-	return c
 }
 
 // JSONEq is a tool to compare the actual JSON value obtained in the test and
@@ -220,33 +280,108 @@ func JSONEq(tb TestingTB, got string) Conclusion {
 // Read is a functional for reading both input and golden files using
 // the appropriate target.
 func (t Tool) Read() (bs []byte) {
+	bs, _ = t.read()
+	return bs
+}
+
+// read reads the data the same way as Read, found is false if the file
+// is not found.
+func (t Tool) read() (bs []byte, found bool) {
 	if t.want != nil {
 		t.test.Logf("golden: read the value from the want field")
 		bs = make([]byte, len(t.want))
 		copy(bs, t.want)
 
-		return bs
+		return bs, true
 	}
 
-	bs, err := t.readFile(t.path())
+	path := t.path()
+	bs, err := t.readFile(path)
+	if os.IsNotExist(err) && t.fallback != nil {
+		var fallback string
+		if fallback, bs, err = t.readFallback(); err == nil {
+			path = fallback
+		}
+	}
 	if os.IsNotExist(err) {
 		const f = "golden: read the value of nil since it is not found file: %s"
 		t.test.Logf(f, t.path())
-		return nil
+		return nil, false
 	} else if err != nil {
 		t.test.Fatalf("golden: %s", err)
 	}
+	if t.sum != "" && t.target != Input {
+		t.verifySum(path, bs)
+	}
 
-	return bs
+	return bs, true
+}
+
+// readFallback reads the file located by the fallback path template and
+// returns its path.
+func (t Tool) readFallback() (string, []byte, error) {
+	path := t.fallbackTool().path()
+	bs, err := t.readFile(path)
+	if err == nil {
+		t.test.Logf("golden: read the value from the fallback file: %s", path)
+	}
+
+	return path, bs, err
 }
 
 // Run is a functional that automates the process of reading the input file
 // of the test bytes and the execution of the input function of testing and
 // checking the results.
 func (t Tool) Run(do func(input []byte) (got []byte, err error)) {
-	bs, err := do(t.SetTarget(Input).Read())
-	t.noError(err)
-	t.Assert(bs)
+	t.assertResult(do(t.SetTarget(Input).Read()))
+}
+
+// assertResult asserts the result of the function of testing, the error
+// fails the test or is compared with the error golden file, see
+// SetErrorGolden.
+func (t Tool) assertResult(got []byte, err error) {
+	if !t.errGolden {
+		t.noError(err)
+		t.Assert(got)
+		return
+	}
+
+	var msg []byte
+	if err != nil {
+		msg = []byte(err.Error())
+	}
+
+	assertAll(t.test, t.Equal(got), t.output("err").Equal(msg))
+}
+
+// SetErrorGolden a setter of the mode in which Run does not fail the test
+// on the error of the function, but compares the text of the error with
+// the error golden file, for example "testdata/TestFoo.err.golden". The
+// absence of the error golden file asserts that the function succeeds,
+// when the golden files are updated, the error golden file of the
+// function that succeeds is deleted. The result of the function is still
+// compared with the golden file, usually it is nil for the failure cases,
+// so the golden file must not exist.
+func (t Tool) SetErrorGolden(enabled bool) Tool {
+	t.errGolden = enabled
+	return t
+}
+
+// SetFallbackPathTemplate a fallback path template setter, if the file is
+// not found by the path template, then the data is read from the file
+// located by the fallback path template. When the golden files are
+// updated, the file in the fallback layout is deleted. It is intended for
+// migration between layouts, for example:
+//
+//	golden.SetTest(t).
+//		SetPathTemplate(golden.ExtensionLastPathTemplate).
+//		SetFallbackPathTemplate(golden.DefaultPathTemplate)
+//
+// The template uses the same fields as SetPathTemplate. An empty text
+// disables the fallback. Panics if the text cannot be parsed.
+func (t Tool) SetFallbackPathTemplate(text string) Tool {
+	t.fallback = t.SetPathTemplate(text).template
+	return t
 }
 
 // SetPrefix a prefix value setter.
@@ -255,8 +390,90 @@ func (t Tool) SetPrefix(prefix string) Tool {
 	return t
 }
 
+// SetFS a setter of the file system from which the golden and input data
+// is read, for example embed.FS, fstest.MapFS or zip.Reader. The paths of
+// the files are slash-separated, for example "testdata/TestFoo.golden".
+// The golden files are still updated in the file system of the operating
+// system, so after the update the data is compared with the value just
+// written instead of the stale copy from the file system. A nil value
+// resets reading to the file system of the operating system.
+func (t Tool) SetFS(fsys fs.FS) Tool {
+	t.readFS = fsys != nil
+	if fsys == nil {
+		t.readFile = ioutil.ReadFile
+		t.glob = filepath.Glob
+		return t
+	}
+
+	t.readFile = func(filename string) ([]byte, error) {
+		return fs.ReadFile(fsys, filepath.ToSlash(filename))
+	}
+	t.glob = func(pattern string) ([]string, error) {
+		matches, err := fs.Glob(fsys, filepath.ToSlash(pattern))
+		for i := range matches {
+			matches[i] = filepath.FromSlash(matches[i])
+		}
+		return matches, err
+	}
+
+	return t
+}
+
+// SetPathTemplate a path template setter, the template is used to build
+// the path to the file containing the test data instead of the default
+// layout `{{.Dir}}/{{.Test}}{{.Prefix}}{{.Ext}}.{{.Target}}`. An empty
+// text resets the template to the default layout.
+//
+// The template is executed with the following fields:
+//
+//	.Dir     the directory for test data, e.g. "testdata"
+//	.Package the base name of the current working directory, e.g. "golden"
+//	.Test    the name of the test, e.g. "TestFoo/subtest"
+//	.Prefix  the prefix with the leading dot or empty, e.g. ".prefix"
+//	.Ext     the extension with the leading dot or empty, e.g. ".json"
+//	.Target  the name of the target, e.g. "golden" or "input"
+//
+// The function replace is also available in the template, it is
+// strings.ReplaceAll, for example `{{replace .Test "/" "-"}}` flattens
+// subtests. Elements of the resulting path are separated by a slash on
+// all operating systems.
+//
+// Panics if the text cannot be parsed.
+func (t Tool) SetPathTemplate(text string) Tool {
+	t.template = nil
+	if text == "" {
+		return t
+	}
+
+	tmpl, err := template.New("path").Funcs(pathFuncs).Parse(text)
+	if err != nil {
+		const msg = "golden: cannot parse path template %q, error: %v"
+		panic(fmt.Sprintf(msg, text, err))
+	}
+	t.template = tmpl
+
+	return t
+}
+
+// SetPortability a setter of the handling of file names that cannot be
+// created on all operating systems, see Portability.
+func (t Tool) SetPortability(p Portability) Tool {
+	t.portability = p
+	return t
+}
+
+// expected returns a copy of the tool that reads the expected data, it is
+// the golden file or the file of the custom target.
+func (t Tool) expected() Tool {
+	if t.target.custom() {
+		return t
+	}
+
+	return t.SetTarget(Golden)
+}
+
 // SetTarget a target value setter.
-func (t Tool) SetTarget(tar target) Tool {
+func (t Tool) SetTarget(tar Target) Tool {
 	t.target = tar
 	return t
 }
@@ -292,7 +509,39 @@ func (t Tool) Update(bs []byte) {
 // the appropriate target.
 func (t Tool) write(bs []byte) {
 	path := t.path()
+	if t.portability == PortabilityCheck {
+		t.checkPortable(path)
+	}
+	t.checkCollision(path)
 	t.mkdir(filepath.Dir(path))
+	t.store(path, bs)
+
+	if t.sum != "" && t.target != Input {
+		t.updateSum(path, bs)
+		if fallback := t.fallbackTool().path(); t.fallback != nil && fallback != path {
+			t.updateSum(fallback, nil)
+		}
+	}
+	if _, hashes := shorten(t.fullPath()); len(hashes) != 0 {
+		t.index(path, hashes, bs == nil)
+	}
+}
+
+// store writes the bytes to the file or deletes the file if the bytes are
+// nil, and deletes the file of the test in the fallback layout.
+func (t Tool) store(path string, bs []byte) {
+	t.put(path, bs)
+
+	if t.fallback != nil {
+		t.removeFallback()
+	}
+}
+
+// put writes the bytes to the file or deletes the file if the bytes are
+// nil, the directory of the file is locked during the change.
+func (t Tool) put(path string, bs []byte) {
+	defer lockPath(filepath.Dir(path))()
+
 	t.test.Logf("golden: start write to file: %s", path)
 	if bs == nil {
 		t.test.Logf("golden: nil value will not be written")
@@ -309,8 +558,27 @@ func (t Tool) write(bs []byte) {
 	}
 }
 
+// removeFallback deletes the file in the fallback layout, so that after
+// the update only the file in the current layout remains.
+func (t Tool) removeFallback() {
+	path := t.fallbackTool().path()
+	if path == t.path() {
+		return
+	}
+
+	fileInfo, err := t.stat(path)
+	if err == nil && !fileInfo.IsDir() {
+		t.test.Logf("golden: the fallback file will be deleted: %s", path)
+		t.noError(t.remove(path))
+	} else if err != nil && !os.IsNotExist(err) {
+		t.noError(err)
+	}
+}
+
 // mkdir the mechanism to create the directory.
 func (t Tool) mkdir(loc string) {
+	defer lockPath(loc)()
+
 	fileInfo, err := t.stat(loc)
 	switch {
 	case err != nil && os.IsNotExist(err):
@@ -331,12 +599,24 @@ func (t Tool) noError(err error) {
 }
 
 // path is getter to get the path to the file containing the test data.
+// Elements of the path that are too long for the file system are shortened.
 func (t Tool) path() (path string) {
+	path, _ = shorten(t.fullPath())
+	return path
+}
+
+// fullPath is getter to get the path to the file containing the test data
+// without shortening.
+func (t Tool) fullPath() (path string) {
+	if t.template != nil {
+		return t.templatePath()
+	}
+
 	format := "%s"
-	args := []interface{}{t.test.Name()}
+	args := []interface{}{t.name()}
 
 	if t.prefix != "" {
-		args = append(args, t.prefix)
+		args = append(args, t.escape(t.prefix))
 	}
 	if t.extension != "" {
 		args = append(args, t.extension)
@@ -350,11 +630,65 @@ func (t Tool) path() (path string) {
 	return filepath.Join(t.dir, fmt.Sprintf(format, args...))
 }
 
-func (t Tool) update(f func() []byte) {
-	if t.flag != nil && *t.flag && t.want == nil {
-		t.test.Logf("golden: updating file: %s", t.path())
-		t.write(f())
+// fallbackTool returns a copy of the tool that uses the fallback path
+// template, the default layout is used if the fallback template is nil.
+func (t Tool) fallbackTool() Tool {
+	t.template, t.fallback = t.fallback, nil
+	return t
+}
+
+// pathFuncs the functions available in the path template.
+var pathFuncs = template.FuncMap{
+	"replace": strings.ReplaceAll,
+}
+
+// pathData the data with which the path template is executed.
+type pathData struct {
+	Dir     string
+	Package string
+	Test    string
+	Prefix  string
+	Ext     string
+	Target  string
+}
+
+// templatePath builds the path to the file containing the test data
+// using the path template.
+func (t Tool) templatePath() string {
+	data := pathData{
+		Dir:    filepath.ToSlash(t.dir),
+		Test:   t.name(),
+		Target: t.target.String(),
 	}
+	if wd, err := os.Getwd(); err == nil {
+		data.Package = filepath.Base(wd)
+	}
+	if t.prefix != "" {
+		data.Prefix = "." + t.escape(t.prefix)
+	}
+	if t.extension != "" {
+		data.Ext = "." + t.extension
+	}
+
+	buf := new(strings.Builder)
+	if err := t.template.Execute(buf, data); err != nil {
+		t.test.Fatalf("golden: cannot execute path template: %s", err)
+	}
+
+	return filepath.Clean(filepath.FromSlash(buf.String()))
+}
+
+func (t Tool) update(f func() []byte) bool {
+	if t.flag == nil || !*t.flag || t.want != nil {
+		return false
+	}
+
+	t.checkUpdateAllowed()
+	printUpdateBanner()
+	t.test.Logf("golden: updating file: %s", t.path())
+	t.write(f())
+
+	return true
 }
 
 func (t Tool) setExtension(ext string) Tool {
@@ -6,10 +6,12 @@ package golden
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -21,6 +23,13 @@ var _goldie = _golden
 func TestMain(m *testing.M) {
 	_goldie.flag = _golden.flag
 	_golden.flag = nil
+	// The tests of the package must behave the same way in and outside
+	// the CI environment.
+	for _, name := range ciEnvNames {
+		if err := os.Unsetenv(name); err != nil {
+			panic(err)
+		}
+	}
 	os.Exit(m.Run())
 }
 
@@ -400,10 +409,177 @@ func TestTool_path(t *testing.T) {
 	}
 }
 
+func TestTool_SetPathTemplate(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	pkg := filepath.Base(wd)
+
+	tests := []struct {
+		name string
+		tool Tool
+		path string
+	}{
+		{
+			name: "default-layout",
+			tool: _golden.SetPathTemplate("{{.Dir}}/{{.Test}}{{.Prefix}}{{.Ext}}.{{.Target}}"),
+			path: "testdata/TestTool_SetPathTemplate/default-layout.golden",
+		},
+		{
+			name: "extension-last",
+			tool: _golden.setExtension("json").SetPathTemplate("{{.Dir}}/{{.Test}}.{{.Target}}{{.Ext}}"),
+			path: "testdata/TestTool_SetPathTemplate/extension-last.golden.json",
+		},
+		{
+			name: "extension-last-input",
+			tool: _golden.setExtension("json").SetTarget(Input).SetPathTemplate("{{.Dir}}/{{.Test}}.{{.Target}}{{.Ext}}"),
+			path: "testdata/TestTool_SetPathTemplate/extension-last-input.input.json",
+		},
+		{
+			name: "group-by-package",
+			tool: _golden.SetPrefix("prefix").SetPathTemplate("{{.Dir}}/{{.Package}}/{{.Test}}/{{.Target}}{{.Prefix}}"),
+			path: "testdata/" + pkg + "/TestTool_SetPathTemplate/group-by-package/golden.prefix",
+		},
+		{
+			name: "flatten-subtests",
+			tool: _golden.SetPathTemplate(`{{.Dir}}/{{replace .Test "/" "-"}}.{{.Target}}`),
+			path: "testdata/TestTool_SetPathTemplate-flatten-subtests.golden",
+		},
+		{
+			name: "reset-to-default",
+			tool: _golden.SetPathTemplate("{{.Target}}").SetPathTemplate(""),
+			path: "testdata/TestTool_SetPathTemplate/reset-to-default.golden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, filepath.FromSlash(tt.path), tt.tool.SetTest(t).path())
+		})
+	}
+
+	t.Run("parsing-error", func(t *testing.T) {
+		const expected = "golden: cannot parse path template \"{{.Dir\", error:" +
+			" template: path:1: unclosed action"
+		assert.PanicsWithValue(t, expected, func() {
+			_golden.SetPathTemplate("{{.Dir")
+		})
+	})
+	t.Run("execution-error", func(t *testing.T) {
+		tb := &bufferTB{name: t.Name()}
+		assert.Panics(t, func() {
+			_golden.SetPathTemplate("{{.Unknown}}").SetTest(tb).path()
+		})
+		assert.Contains(t, tb.String(), "golden: cannot execute path template:")
+	})
+}
+
+func TestTool_SetFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"testdata/TestTool_SetFS/embedded.golden": {Data: []byte("golden")},
+		"testdata/TestTool_SetFS/embedded.input":  {Data: []byte("input")},
+	}
+
+	t.Run("embedded", func(t *testing.T) {
+		tb := &bufferTB{name: t.Name()}
+		tool := _golden.SetTest(tb).SetFS(fsys)
+		assert.Equal(t, "golden", string(tool.Read()))
+		assert.Equal(t, "input", string(tool.SetTarget(Input).Read()))
+		assert.False(t, tool.Equal([]byte("golden")).Failed())
+
+		files, err := tool.glob(filepath.Join("testdata", "TestTool_SetFS", "*.input"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join("testdata", "TestTool_SetFS", "embedded.input")}, files)
+	})
+	t.Run("update", func(t *testing.T) {
+		tb := &bufferTB{name: "TestTool_SetFS/embedded"}
+		tool := _golden.SetTest(tb).SetFS(fsys)
+		tool.flag = &[]bool{true}[0]
+
+		var written []byte
+		tool.mkdirAll = func(path string, perm os.FileMode) error { return nil }
+		tool.stat = func(name string) (os.FileInfo, error) {
+			return &FakeStat{name: name, isDir: true}, nil
+		}
+		tool.writeFile = func(filename string, data []byte, perm os.FileMode) error {
+			written = data
+			return nil
+		}
+
+		assert.False(t, tool.Equal([]byte("changed")).Failed())
+		assert.Equal(t, "changed", string(written))
+		assert.Equal(t, "golden", string(tool.Read()))
+		assert.False(t, tool.SetStorage(nil).readFS)
+	})
+	t.Run("not-found", func(t *testing.T) {
+		tb := &bufferTB{name: t.Name()}
+		assert.Nil(t, _golden.SetTest(tb).SetFS(fsys).Read())
+		assert.Contains(t, tb.String(), "golden: read the value of nil since it is not found file:")
+	})
+	t.Run("reset", func(t *testing.T) {
+		tb := &bufferTB{name: t.Name()}
+		assert.Nil(t, _golden.SetTest(tb).SetFS(fsys).SetFS(nil).SetTarget(Input).Read())
+	})
+}
+
+func TestTool_SetFallbackPathTemplate(t *testing.T) {
+	t.Run("read-from-fallback", func(t *testing.T) {
+		tb := &bufferTB{name: t.Name()}
+		tool := _golden.SetTest(tb).setExtension("json").
+			SetPathTemplate(ExtensionLastPathTemplate).
+			SetFallbackPathTemplate(DefaultPathTemplate)
+		tool.readFile = func(filename string) ([]byte, error) {
+			if filename == filepath.FromSlash("testdata/TestTool_SetFallbackPathTemplate/read-from-fallback.json.golden") {
+				return []byte("golden"), nil
+			}
+			return nil, os.ErrNotExist
+		}
+
+		assert.Equal(t, "golden", string(tool.Read()))
+		assert.Contains(t, tb.String(), "golden: read the value from the fallback file:")
+	})
+	t.Run("read-not-found", func(t *testing.T) {
+		tb := &bufferTB{name: t.Name()}
+		tool := _golden.SetTest(tb).
+			SetPathTemplate(ExtensionLastPathTemplate).
+			SetFallbackPathTemplate(DefaultPathTemplate)
+		tool.readFile = helperOSReadFile(t, nil, os.ErrNotExist)
+
+		assert.Nil(t, tool.Read())
+	})
+	t.Run("update-removes-fallback", func(t *testing.T) {
+		tb := &bufferTB{name: t.Name()}
+		tool := _golden.SetTest(tb).setExtension("json").
+			SetPathTemplate(ExtensionLastPathTemplate).
+			SetFallbackPathTemplate(DefaultPathTemplate)
+		tool.flag = &[]bool{true}[0]
+
+		var written, removed []string
+		tool.mkdirAll = func(path string, perm os.FileMode) error { return nil }
+		tool.stat = func(name string) (os.FileInfo, error) {
+			return &FakeStat{name: name, isDir: filepath.Ext(name) == ""}, nil
+		}
+		tool.writeFile = func(filename string, data []byte, perm os.FileMode) error {
+			written = append(written, filepath.ToSlash(filename))
+			return nil
+		}
+		tool.remove = func(name string) error {
+			removed = append(removed, filepath.ToSlash(name))
+			return nil
+		}
+
+		tool.Update([]byte("golden"))
+		assert.Equal(t, []string{
+			"testdata/TestTool_SetFallbackPathTemplate/update-removes-fallback.golden.json",
+		}, written)
+		assert.Equal(t, []string{
+			"testdata/TestTool_SetFallbackPathTemplate/update-removes-fallback.json.golden",
+		}, removed)
+	})
+}
+
 func TestTool_Read(t *testing.T) {
 	type args struct {
 		test *bufferTB
-		tar  target
+		tar  Target
 	}
 	type readFile struct {
 		error error
@@ -531,11 +707,60 @@ func TestTool_Run(t *testing.T) {
 	}
 }
 
+func TestTool_SetErrorGolden(t *testing.T) {
+	errPath := filepath.Join("testdata", "TestFoo.err.golden")
+	fail := func([]byte) ([]byte, error) { return nil, errors.New("syntax error at 1:2") }
+	succeed := func([]byte) ([]byte, error) { return []byte("ok"), nil }
+
+	t.Run("error-matches", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(errPath, []byte("syntax error at 1:2"), 0644))
+
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s).SetErrorGolden(true)
+		assert.NotPanics(t, func() { tool.Run(fail) })
+	})
+	t.Run("error-file-of-success", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.golden"), []byte("ok"), 0644))
+		assert.NoError(t, s.WriteFile(errPath, []byte("syntax error at 1:2"), 0644))
+
+		tb := &bufferTB{name: "TestFoo"}
+		tool := _golden.SetTest(tb).SetStorage(s).SetErrorGolden(true)
+		assert.Panics(t, func() { tool.Run(succeed) })
+		assert.Contains(t, tb.String(), "syntax error at 1:2")
+	})
+	t.Run("disabled", func(t *testing.T) {
+		tb := &bufferTB{name: "TestFoo"}
+		tool := _golden.SetTest(tb).SetStorage(NewMemoryStorage())
+		assert.Panics(t, func() { tool.Run(fail) })
+		assert.Contains(t, tb.String(), "syntax error at 1:2")
+	})
+	t.Run("update", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s).SetErrorGolden(true)
+		tool.flag = &[]bool{true}[0]
+
+		tool.Run(fail)
+		bs, err := s.ReadFile(errPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "syntax error at 1:2", string(bs))
+
+		tool.Run(succeed)
+		_, err = s.Stat(errPath)
+		assert.True(t, os.IsNotExist(err))
+		bs, err = s.ReadFile(filepath.Join("testdata", "TestFoo.golden"))
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", string(bs))
+	})
+}
+
 func TestTool_SetTarget(t *testing.T) {
 	tests := []struct {
 		name   string
 		tool   Tool
-		target target
+		target Target
 		want   Tool
 	}{
 		{
@@ -658,7 +883,7 @@ func TestTool_Update(t *testing.T) {
 func TestTool_write(t *testing.T) {
 	type args struct {
 		test  *bufferTB
-		tar   target
+		tar   Target
 		bytes []byte
 	}
 	type stat struct {
@@ -1324,15 +1549,15 @@ func TestTool_SetWant(t *testing.T) {
 
 func Test_getUpdateEnv(t *testing.T) {
 	t.Run("received false", func(t *testing.T) {
-		assert.NoError(t, os.Setenv(updateEnvName, "false"))
+		t.Setenv(updateEnvName, "false")
 		assert.False(t, getUpdateEnv())
 	})
 	t.Run("received true", func(t *testing.T) {
-		assert.NoError(t, os.Setenv(updateEnvName, "true"))
+		t.Setenv(updateEnvName, "true")
 		assert.True(t, getUpdateEnv())
 	})
 	t.Run("parsing error", func(t *testing.T) {
-		assert.NoError(t, os.Setenv(updateEnvName, "folse"))
+		t.Setenv(updateEnvName, "folse")
 		const expected = "cannot parse flag \"GOLDEN_UPDATE\", error:" +
 			" strconv.ParseBool: parsing \"folse\": invalid syntax"
 		assert.PanicsWithValue(
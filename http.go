@@ -0,0 +1,220 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// HTTPExt the extension of the golden files of the responses of the HTTP
+// handlers and the input files of the requests.
+const HTTPExt = "http"
+
+// ServeHTTP serves the request by the handler and compares the response
+// with the golden file. See Tool.ServeHTTP.
+func ServeHTTP(t TestingTB, handler http.Handler, req *http.Request, headers ...string) {
+	if h, ok := t.(testingHelper); ok {
+		h.Helper()
+	}
+	SetTest(t).ServeHTTP(handler, req, headers...)
+}
+
+// ServeHTTP serves the request by the handler, records the response and
+// compares it with the golden file in the HTTP wire format, for example
+// "testdata/TestFoo.http.golden":
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//
+//	{
+//		"id": 1
+//	}
+//
+// The status line and the headers are compared exactly, only the
+// Content-Type header and the headers listed in the arguments are
+// included, because the other headers like Date usually change from run
+// to run. The JSON body is formatted and compared as JSON, so the order of
+// the keys does not matter, the other bodies are compared as is.
+func (t Tool) ServeHTTP(handler http.Handler, req *http.Request, headers ...string) {
+	if h, ok := t.test.(testingHelper); ok {
+		h.Helper()
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	res := rec.Result()
+
+	body, err := io.ReadAll(res.Body)
+	t.noError(err)
+	isJSON := isJSONMediaType(res.Header.Get("Content-Type"))
+	if isJSON && len(body) != 0 {
+		body = []byte(jsonFormatter(t.test, string(body)))
+	}
+	head := formatResponseHead(res, headers)
+	got := append([]byte(head+"\n"), body...)
+
+	data := func() []byte { return got }
+	t.setExtension(HTTPExt).compare(data, func(i *interceptor, want []byte) bool {
+		wantHead, wantBody := splitResponse(want)
+		ok := assert.Equal(i, wantHead, head)
+		if isJSON && len(body) != 0 {
+			return assert.JSONEq(i, wantBody, string(body)) && ok
+		}
+		return assert.Equal(i, wantBody, string(body)) && ok
+	}).FailNow()
+}
+
+// ReadRequest reads the HTTP request from the input file of the test. See
+// Tool.ReadRequest.
+func ReadRequest(t TestingTB) *http.Request {
+	if h, ok := t.(testingHelper); ok {
+		h.Helper()
+	}
+	return SetTest(t).ReadRequest()
+}
+
+// ReadRequest reads the input file of the test in the raw HTTP request
+// format, for example "testdata/TestFoo.http.input", and parses it into
+// the request suitable for passing to the handler:
+//
+//	POST /users HTTP/1.1
+//	Content-Type: application/json
+//
+//	{"name": "gopher"}
+//
+// The body is the rest of the file after the empty line, if there is no
+// Content-Length header, otherwise it is limited by the header. Like in
+// httptest.NewRequest, the host is "example.com" if it is not set and the
+// remote address is "192.0.2.1:1234". The test fails if the input file
+// cannot be parsed.
+func (t Tool) ReadRequest() *http.Request {
+	if h, ok := t.test.(testingHelper); ok {
+		h.Helper()
+	}
+
+	input := t.SetTarget(Input).setExtension(HTTPExt)
+	req, err := parseRequest(input.Read())
+	if err != nil {
+		t.test.Fatalf("golden: cannot parse the HTTP request of the input file %s: %v", input.path(), err)
+	}
+	return req
+}
+
+// RunHandler reads the HTTP request from the input file of the test, serves
+// it by the handler and compares the response with the golden file. See
+// Tool.RunHandler.
+func RunHandler(t TestingTB, handler http.Handler, headers ...string) {
+	if h, ok := t.(testingHelper); ok {
+		h.Helper()
+	}
+	SetTest(t).RunHandler(handler, headers...)
+}
+
+// RunHandler reads the HTTP request from the input file of the test, serves
+// it by the handler and compares the response with the golden file, so the
+// test case is fully described by the files, for example
+// "testdata/TestFoo.http.input" and "testdata/TestFoo.http.golden". See
+// Tool.ReadRequest and Tool.ServeHTTP.
+func (t Tool) RunHandler(handler http.Handler, headers ...string) {
+	if h, ok := t.test.(testingHelper); ok {
+		h.Helper()
+	}
+	t.ServeHTTP(handler, t.ReadRequest(), headers...)
+}
+
+// parseRequest parses the request in the raw HTTP request format, the lines
+// can be separated by LF or CRLF.
+func parseRequest(bs []byte) (*http.Request, error) {
+	head, body := bs, []byte{}
+	for i := 0; i < len(bs); {
+		j := bytes.IndexByte(bs[i:], '\n')
+		if j < 0 {
+			break
+		}
+		if line := bytes.TrimSuffix(bs[i:i+j], []byte("\r")); len(line) == 0 && i > 0 {
+			head, body = bs[:i], bs[i+j+1:]
+			break
+		}
+		i += j + 1
+	}
+	if !bytes.HasSuffix(head, []byte("\n")) {
+		head = append(head[:len(head):len(head)], '\n')
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(io.MultiReader(
+		bytes.NewReader(head), strings.NewReader("\r\n"),
+	)))
+	if err != nil {
+		return nil, err
+	}
+
+	if cl := req.Header.Get("Content-Length"); cl != "" {
+		n, err := strconv.Atoi(cl)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid Content-Length: %q", cl)
+		}
+		if n > len(body) {
+			return nil, fmt.Errorf("the body is shorter than the Content-Length: %d", n)
+		}
+		body = body[:n]
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	if req.Host == "" {
+		req.Host = "example.com"
+	}
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	return req, nil
+}
+
+// formatResponseHead formats the status line and the selected headers of
+// the response, the Content-Type header is always selected.
+func formatResponseHead(res *http.Response, headers []string) string {
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "%s %s\n", res.Proto, res.Status)
+
+	seen := map[string]bool{}
+	for _, name := range append([]string{"Content-Type"}, headers...) {
+		name = http.CanonicalHeaderKey(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		for _, value := range res.Header.Values(name) {
+			fmt.Fprintf(buf, "%s: %s\n", name, value)
+		}
+	}
+
+	return buf.String()
+}
+
+// splitResponse splits the response in the wire format into the head and
+// the body separated by the empty line.
+func splitResponse(bs []byte) (head, body string) {
+	i := bytes.Index(bs, []byte("\n\n"))
+	if i < 0 {
+		return string(bs), ""
+	}
+	return string(bs[:i+1]), string(bs[i+2:])
+}
+
+// isJSONMediaType reports whether the media type of the content type is
+// JSON, for example "application/json" or "application/problem+json".
+func isJSONMediaType(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mt == "application/json" || strings.HasSuffix(mt, "+json"))
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTool_ServeHTTP(t *testing.T) {
+	path := filepath.Join("testdata", "TestFoo.http.golden")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Add("X-Request-Id", "1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"name":"gopher","id":1}`))
+	})
+	newRequest := func() *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/users", nil)
+	}
+	const golden = "HTTP/1.1 201 Created\n" +
+		"Content-Type: application/json\n" +
+		"X-Request-Id: 1\n" +
+		"\n" +
+		"{\n\t\"id\": 1,\n\t\"name\": \"gopher\"\n}"
+
+	t.Run("update", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		tool.flag = &[]bool{true}[0]
+		tool.ServeHTTP(handler, newRequest(), "x-request-id")
+
+		bs, err := s.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, golden, string(bs))
+	})
+	t.Run("json-body", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(path, []byte("HTTP/1.1 201 Created\n"+
+			"Content-Type: application/json\n"+
+			"X-Request-Id: 1\n"+
+			"\n"+
+			`{"name": "gopher", "id": 1}`), 0644))
+
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		assert.NotPanics(t, func() { tool.ServeHTTP(handler, newRequest(), "X-Request-Id") })
+	})
+	t.Run("head-mismatch", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(path, []byte(golden), 0644))
+
+		tb := &bufferTB{name: "TestFoo"}
+		tool := _golden.SetTest(tb).SetStorage(s)
+		assert.Panics(t, func() { tool.ServeHTTP(handler, newRequest()) })
+		assert.Contains(t, tb.String(), "-X-Request-Id: 1")
+	})
+	t.Run("text-body", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		tool.flag = &[]bool{true}[0]
+		tool.ServeHTTP(http.NotFoundHandler(), newRequest())
+
+		bs, err := s.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "HTTP/1.1 404 Not Found\n"+
+			"Content-Type: text/plain; charset=utf-8\n"+
+			"\n"+
+			"404 page not found\n", string(bs))
+	})
+}
+
+func Test_isJSONMediaType(t *testing.T) {
+	assert.True(t, isJSONMediaType("application/json"))
+	assert.True(t, isJSONMediaType("application/json; charset=utf-8"))
+	assert.True(t, isJSONMediaType("application/problem+json"))
+	assert.False(t, isJSONMediaType("text/plain"))
+	assert.False(t, isJSONMediaType(""))
+}
+
+func Test_parseRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		method  string
+		url     string
+		host    string
+		body    string
+		wantErr string
+	}{
+		{
+			name:   "body-without-content-length",
+			raw:    "POST /users?x=1 HTTP/1.1\nContent-Type: application/json\n\n{\"name\": \"gopher\"}\n",
+			method: http.MethodPost,
+			url:    "/users?x=1",
+			host:   "example.com",
+			body:   "{\"name\": \"gopher\"}\n",
+		},
+		{
+			name:   "content-length",
+			raw:    "PUT /users/1 HTTP/1.1\r\nHost: api.test\r\nContent-Length: 2\r\n\r\nokignored",
+			method: http.MethodPut,
+			url:    "/users/1",
+			host:   "api.test",
+			body:   "ok",
+		},
+		{
+			name:   "no-body",
+			raw:    "GET / HTTP/1.1",
+			method: http.MethodGet,
+			url:    "/",
+			host:   "example.com",
+		},
+		{
+			name:    "short-body",
+			raw:     "POST / HTTP/1.1\nContent-Length: 5\n\nok",
+			wantErr: "the body is shorter than the Content-Length: 5",
+		},
+		{
+			name:    "malformed",
+			raw:     "",
+			wantErr: `malformed HTTP request ""`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := parseRequest([]byte(tt.raw))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.method, req.Method)
+			assert.Equal(t, tt.url, req.URL.String())
+			assert.Equal(t, tt.host, req.Host)
+			assert.Equal(t, int64(len(tt.body)), req.ContentLength)
+			body, err := io.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
+
+func TestTool_RunHandler(t *testing.T) {
+	s := NewMemoryStorage()
+	assert.NoError(t, s.MkdirAll("testdata", 0755))
+	assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.http.input"),
+		[]byte("POST /echo HTTP/1.1\nContent-Type: text/plain\n\nhello"), 0644))
+	assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.http.golden"),
+		[]byte("HTTP/1.1 200 OK\nContent-Type: text/plain\n\nPOST /echo hello"), 0644))
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + " " + string(body)))
+	})
+
+	t.Run("successful", func(t *testing.T) {
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		assert.NotPanics(t, func() { tool.RunHandler(echo) })
+	})
+	t.Run("missing-input", func(t *testing.T) {
+		tb := &bufferTB{name: "TestBar"}
+		tool := _golden.SetTest(tb).SetStorage(s)
+		assert.Panics(t, func() { tool.RunHandler(echo) })
+		assert.Contains(t, tb.String(), "golden: cannot parse the HTTP request of the input file "+
+			filepath.Join("testdata", "TestBar.http.input"))
+	})
+}
@@ -5,6 +5,7 @@
 package integration
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,6 +29,15 @@ func TestJSONEq(t *testing.T) {
 	})
 }
 
+func TestRunDir(t *testing.T) {
+	var names []string
+	golden.RunDir(t, "testdata/TestRunDir", func(t *testing.T, input []byte) ([]byte, error) {
+		names = append(names, t.Name())
+		return bytes.ToUpper(input), nil
+	})
+	assert.Equal(t, []string{"TestRunDir/golden", "TestRunDir/hello"}, names)
+}
+
 // testTree needed to run a test function in tests with three levels of nesting.
 func testTree(t *testing.T, f func(t *testing.T)) {
 	// Simple test data without structure nesting.
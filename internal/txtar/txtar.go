@@ -0,0 +1,122 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package txtar implements a trivial text-based file archive format compatible
+with golang.org/x/tools/txtar, without adding it as a dependency.
+
+The archive begins with an optional comment, followed by the sequence of
+files. Each file is introduced by a marker line "-- NAME --", the data of
+the file is all the lines up to the next marker line or the end of the
+archive:
+
+	comment
+	-- foo.golden --
+	data of foo.golden
+	-- bar/baz.input --
+	data of bar/baz.input
+*/
+package txtar
+
+import (
+	"bytes"
+	"strings"
+)
+
+var (
+	markerPrefix = []byte("-- ")
+	markerSuffix = []byte(" --")
+	newline      = []byte("\n")
+)
+
+// Archive is a collection of files.
+type Archive struct {
+	Comment []byte
+	Files   []File
+}
+
+// File is a single file in an archive.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Format returns the serialized form of the archive. It is assumed that
+// the archive data structure is well-formed: the comment and the data of
+// the files do not contain marker lines, and the names of the files are
+// not empty. The missing final newline is added to the comment and data.
+func Format(a *Archive) []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(fixNewline(a.Comment))
+	for _, f := range a.Files {
+		buf.WriteString("-- " + f.Name + " --\n")
+		buf.Write(fixNewline(f.Data))
+	}
+
+	return buf.Bytes()
+}
+
+// Parse parses the serialized form of the archive.
+func Parse(data []byte) *Archive {
+	a := new(Archive)
+	var name string
+	a.Comment, name, data = findMarker(data)
+	for name != "" {
+		f := File{Name: name}
+		f.Data, name, data = findMarker(data)
+		a.Files = append(a.Files, f)
+	}
+
+	return a
+}
+
+// HasMarker reports whether the data contains a marker line, such data
+// cannot be stored in the archive.
+func HasMarker(data []byte) bool {
+	_, name, _ := findMarker(data)
+	return name != ""
+}
+
+// findMarker finds the next marker line in data, returning the data
+// before the marker, the name from the marker and the data after the
+// marker. If there is no marker, it returns data, "", nil.
+func findMarker(data []byte) (before []byte, name string, after []byte) {
+	var i int
+	for {
+		if name, after = isMarker(data[i:]); name != "" {
+			return data[:i], name, after
+		}
+
+		j := bytes.Index(data[i:], newline)
+		if j < 0 {
+			return data, "", nil
+		}
+		i += j + 1
+	}
+}
+
+// isMarker checks whether data begins with a marker line, if so, it
+// returns the name from the line and the data after the line.
+func isMarker(data []byte) (name string, after []byte) {
+	if !bytes.HasPrefix(data, markerPrefix) {
+		return "", nil
+	}
+	if i := bytes.Index(data, newline); i >= 0 {
+		data, after = data[:i], data[i+1:]
+	}
+	if !(bytes.HasSuffix(data, markerSuffix) && len(data) >= 2*len(markerPrefix)) {
+		return "", nil
+	}
+
+	return strings.TrimSpace(string(data[len(markerPrefix) : len(data)-len(markerSuffix)])), after
+}
+
+// fixNewline adds the final newline to the non-empty data if it is missing.
+func fixNewline(data []byte) []byte {
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data[:len(data):len(data)], '\n')
+	}
+
+	return data
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package txtar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte("comment1\ncomment2\n" +
+		"-- one --\none\n" +
+		"-- two.golden --\ntwo\nlines\n" +
+		"-- empty --\n" +
+		"-- last --\nno newline")
+
+	a := Parse(data)
+	assert.Equal(t, "comment1\ncomment2\n", string(a.Comment))
+	assert.Equal(t, []File{
+		{Name: "one", Data: []byte("one\n")},
+		{Name: "two.golden", Data: []byte("two\nlines\n")},
+		{Name: "empty", Data: []byte{}},
+		{Name: "last", Data: []byte("no newline")},
+	}, a.Files)
+}
+
+func TestFormat(t *testing.T) {
+	a := &Archive{
+		Comment: []byte("comment"),
+		Files: []File{
+			{Name: "one", Data: []byte("one\n")},
+			{Name: "two", Data: []byte("two")},
+			{Name: "empty"},
+		},
+	}
+
+	want := "comment\n-- one --\none\n-- two --\ntwo\n-- empty --\n"
+	assert.Equal(t, want, string(Format(a)))
+	assert.Equal(t, want, string(Format(Parse([]byte(want)))))
+}
+
+func TestHasMarker(t *testing.T) {
+	assert.False(t, HasMarker([]byte("data\n-- not a marker\n")))
+	assert.False(t, HasMarker([]byte("data -- x --\n")))
+	assert.True(t, HasMarker([]byte("-- x --")))
+	assert.True(t, HasMarker([]byte("data\n-- x --\ndata")))
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// pathLocks the in-process locks of the paths by the absolute paths.
+var pathLocks sync.Map
+
+// lockPath locks the path for exclusive use by the goroutines of the process,
+// for example parallel subtests, and where it is supported by the processes,
+// for example the test binaries of several packages started by `go test -p`
+// that share the directory for test data. The advisory file lock is taken on
+// the path or on its nearest existing parent directory. The locks are not
+// reentrant. Returns the function that unlocks the path.
+func lockPath(path string) (unlock func()) {
+	key := filepath.Clean(path)
+	if abs, err := filepath.Abs(key); err == nil {
+		key = abs
+	}
+
+	v, _ := pathLocks.LoadOrStore(key, new(sync.Mutex))
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+
+	unlockFile := func() {}
+	for p := key; ; p = filepath.Dir(p) {
+		if f, err := os.Open(p); err == nil {
+			unlockFile = lockFile(f)
+			break
+		}
+		if p == filepath.Dir(p) {
+			break
+		}
+	}
+
+	return func() {
+		unlockFile()
+		mu.Unlock()
+	}
+}
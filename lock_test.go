@@ -0,0 +1,81 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_lockPath(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{dir, filepath.Join(dir, "not-exist", "dir")}
+
+	for _, path := range paths {
+		var counter int
+		wg := new(sync.WaitGroup)
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					unlock := lockPath(path)
+					counter++
+					unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 16*100, counter)
+	}
+}
+
+// TestTool_Assert_parallel checks that the update of the golden files is
+// safe under t.Parallel, it is intended to run with the race detector.
+func TestTool_Assert_parallel(t *testing.T) {
+	const levels, count = 4, 8
+	dir := t.TempDir()
+	sumFile := filepath.Join(dir, SumFileName)
+
+	// The compare phase reads the golden files written by the update phase.
+	tool := _golden.SetSumFile(sumFile).
+		SetPathTemplate(`{{.Dir}}/{{replace .Test "/compare/" "/update/"}}.{{.Target}}`)
+	tool.dir = dir
+
+	runAll := func(t *testing.T, tool Tool) {
+		for i := 0; i < levels; i++ {
+			t.Run(fmt.Sprint("level-", i), func(t *testing.T) {
+				t.Parallel()
+				for j := 0; j < count; j++ {
+					t.Run(fmt.Sprint(j), func(t *testing.T) {
+						t.Parallel()
+						name := strings.Replace(t.Name(), "/compare/", "/update/", 1)
+						tool.SetTest(t).Assert([]byte(name))
+					})
+				}
+			})
+		}
+	}
+
+	t.Run("update", func(t *testing.T) {
+		tool.flag = &[]bool{true}[0]
+		runAll(t, tool)
+	})
+
+	bs, err := ioutil.ReadFile(sumFile)
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(string(bs)), "\n"), levels*count)
+
+	t.Run("compare", func(t *testing.T) {
+		tool.flag = nil
+		runAll(t, tool)
+	})
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Missing defines how Equal and JSONEq handle the missing golden file when
+// the actual value is not nil. The nil actual value always matches the
+// missing golden file.
+type Missing uint
+
+const (
+	// MissingDefault is MissingFail in the CI environment and MissingNil
+	// otherwise.
+	MissingDefault Missing = iota
+	// MissingNil the missing golden file is read as nil and compared with
+	// the actual value.
+	MissingNil
+	// MissingFail the missing golden file is a distinct failure with the
+	// description of how to create it.
+	MissingFail
+	// MissingCreate the missing golden file is created from the actual value
+	// on the first run, so the comparison succeeds.
+	MissingCreate
+	// MissingCreateSkip the missing golden file is created as MissingCreate
+	// and the test is skipped, so the run shows the tests without golden
+	// files. The test is not skipped if TestingTB does not have the SkipNow
+	// method.
+	MissingCreateSkip
+	// MissingCreateFail the missing golden file is created as MissingCreate
+	// and the comparison fails, so the created file is reviewed before the
+	// test is run again.
+	MissingCreateFail
+)
+
+// testingSkipper the interface of the tests that can be skipped.
+type testingSkipper interface {
+	SkipNow()
+}
+
+// SetMissing a setter of the handling of the missing golden file, see
+// Missing.
+func (t Tool) SetMissing(m Missing) Tool {
+	t.missing = m
+	return t
+}
+
+// missingMode returns the handling of the missing golden file taking into
+// account the environment. The golden files are not created in the CI
+// environment unless it is explicitly allowed, see checkUpdateAllowed.
+func (t Tool) missingMode() Missing {
+	ci := ciEnv() != ""
+	switch t.missing {
+	case MissingDefault:
+		if ci {
+			return MissingFail
+		}
+		return MissingNil
+	case MissingCreate, MissingCreateSkip, MissingCreateFail:
+		if ci && !getUpdateInCIEnv() {
+			return MissingFail
+		}
+	}
+
+	return t.missing
+}
+
+// handleMissing handles the missing golden file according to the mode,
+// the data returns the data of the file to create. If ok is true, then the
+// conclusion is the result of the comparison.
+func (t Tool) handleMissing(data func() []byte) (c conclusion, ok bool) {
+	mode := t.missingMode()
+	switch mode {
+	case MissingFail:
+		return t.missingConclusion(), true
+	case MissingCreate, MissingCreateSkip, MissingCreateFail:
+		t.create(data())
+	}
+
+	switch mode {
+	case MissingCreateSkip:
+		if s, ok := t.test.(testingSkipper); ok {
+			t.test.Logf("golden: the test is skipped since the golden file was created")
+			s.SkipNow()
+		}
+	case MissingCreateFail:
+		const f = "golden: the missing golden file %s was created," +
+			" review it and run the test again"
+		c = newConclusion(t.test)
+		c.diff = interceptor(fmt.Sprintf(f, t.path()))
+		return c, true
+	}
+
+	return c, false
+}
+
+// missingConclusion returns the failed conclusion describing how to create
+// the missing golden file.
+func (t Tool) missingConclusion() conclusion {
+	test := strings.SplitN(t.test.Name(), "/", 2)[0]
+	const f = "golden: the golden file %s does not exist, create it by running" +
+		" the test in the update mode, for example:\n\n" +
+		"\t%s=true go test -run '^%s$' ./...\n"
+
+	c := newConclusion(t.test)
+	c.diff = interceptor(fmt.Sprintf(f, t.path(), updateEnvName, test))
+
+	return c
+}
+
+// create writes the missing golden file.
+func (t Tool) create(bs []byte) {
+	t.test.Logf("golden: creating the missing golden file: %s", t.path())
+	t.write(bs)
+}
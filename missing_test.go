@@ -0,0 +1,138 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTool_SetMissing(t *testing.T) {
+	newTool := func(t *testing.T, m Missing) (Tool, *bufferTB, *MemoryStorage) {
+		s := NewMemoryStorage()
+		tb := &bufferTB{name: t.Name()}
+		return _golden.SetTest(tb).SetStorage(s).SetMissing(m), tb, s
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		tool, tb, _ := newTool(t, MissingNil)
+		c := tool.Equal([]byte("golden"))
+		assert.True(t, c.Failed())
+		c.Fail()
+		assert.Contains(t, tb.String(), `expected: "[]byte(nil)"`)
+	})
+	t.Run("fail", func(t *testing.T) {
+		tool, tb, _ := newTool(t, MissingFail)
+		c := tool.Equal([]byte("golden"))
+		assert.True(t, c.Failed())
+		c.Fail()
+		assert.Contains(t, tb.String(), "golden: the golden file testdata/TestTool_SetMissing/fail.golden"+
+			" does not exist, create it by running the test in the update mode, for example:\n\n"+
+			"\tGOLDEN_UPDATE=true go test -run '^TestTool_SetMissing$' ./...\n")
+		assert.NotContains(t, tb.String(), "[]byte(nil)")
+	})
+	t.Run("fail-json", func(t *testing.T) {
+		tool, _, _ := newTool(t, MissingFail)
+		assert.True(t, tool.JSONEq(`{}`).Failed())
+	})
+	t.Run("fail-nil-matches-missing", func(t *testing.T) {
+		tool, _, _ := newTool(t, MissingFail)
+		assert.False(t, tool.Equal(nil).Failed())
+	})
+	t.Run("default-in-ci", func(t *testing.T) {
+		helperUnsetCIEnv(t)
+		assert.NoError(t, os.Setenv("CI", "true"))
+		tool, _, _ := newTool(t, MissingDefault)
+		assert.Equal(t, MissingFail, tool.missingMode())
+	})
+	t.Run("default-outside-ci", func(t *testing.T) {
+		helperUnsetCIEnv(t)
+		tool, _, _ := newTool(t, MissingDefault)
+		assert.Equal(t, MissingNil, tool.missingMode())
+	})
+	t.Run("create", func(t *testing.T) {
+		tool, tb, s := newTool(t, MissingCreate)
+		assert.False(t, tool.Equal([]byte("golden")).Failed())
+		assert.Contains(t, tb.String(), "golden: creating the missing golden file: testdata/TestTool_SetMissing/create.golden")
+
+		bs, err := s.ReadFile(filepath.Join("testdata", "TestTool_SetMissing", "create.golden"))
+		assert.NoError(t, err)
+		assert.Equal(t, "golden", string(bs))
+		assert.True(t, tool.Equal([]byte("Z29sZGVu")).Failed(), "the created file must be compared")
+	})
+	t.Run("create-json", func(t *testing.T) {
+		tool, _, s := newTool(t, MissingCreate)
+		assert.False(t, tool.JSONEq(`{"a":1}`).Failed())
+
+		bs, err := s.ReadFile(filepath.Join("testdata", "TestTool_SetMissing", "create-json.json.golden"))
+		assert.NoError(t, err)
+		assert.Equal(t, "{\n\t\"a\": 1\n}", string(bs))
+	})
+	t.Run("create-skip", func(t *testing.T) {
+		tool, _, s := newTool(t, MissingCreateSkip)
+		tb := &skipperTB{bufferTB: bufferTB{name: t.Name()}}
+		assert.Panics(t, func() { tool.SetTest(tb).Equal([]byte("golden")) })
+		assert.True(t, tb.skipped)
+
+		_, err := s.ReadFile(filepath.Join("testdata", "TestTool_SetMissing", "create-skip.golden"))
+		assert.NoError(t, err)
+	})
+	t.Run("create-skip-not-supported", func(t *testing.T) {
+		tool, _, _ := newTool(t, MissingCreateSkip)
+		assert.False(t, tool.Equal([]byte("golden")).Failed())
+	})
+	t.Run("create-fail", func(t *testing.T) {
+		tool, tb, s := newTool(t, MissingCreateFail)
+		c := tool.Equal([]byte("golden"))
+		assert.True(t, c.Failed())
+		c.Fail()
+		assert.Contains(t, tb.String(), "golden: the missing golden file"+
+			" testdata/TestTool_SetMissing/create-fail.golden was created, review it and run the test again")
+
+		_, err := s.ReadFile(filepath.Join("testdata", "TestTool_SetMissing", "create-fail.golden"))
+		assert.NoError(t, err)
+		assert.False(t, tool.Equal([]byte("golden")).Failed(), "the second run must succeed")
+	})
+	t.Run("create-refused-in-ci", func(t *testing.T) {
+		helperUnsetCIEnv(t)
+		assert.NoError(t, os.Setenv("CI", "true"))
+		tool, _, s := newTool(t, MissingCreate)
+		assert.True(t, tool.Equal([]byte("golden")).Failed())
+
+		_, err := s.ReadFile(filepath.Join("testdata", "TestTool_SetMissing", "create-refused-in-ci.golden"))
+		assert.True(t, os.IsNotExist(err))
+
+		assert.NoError(t, os.Setenv(updateInCIEnvName, "true"))
+		assert.False(t, tool.Equal([]byte("golden")).Failed())
+	})
+}
+
+func TestTool_checkCollision(t *testing.T) {
+	s := NewMemoryStorage()
+	template := `{{.Dir}}/collision-{{replace .Test "/" "-"}}.{{.Target}}`
+	first := _golden.SetTest(&bufferTB{name: "TestCollision/a-b"}).SetStorage(s).SetPathTemplate(template)
+	first.flag = &[]bool{true}[0]
+	first.Update([]byte("golden"))
+	first.Update([]byte("golden"))
+
+	tb := &bufferTB{name: "TestCollision-a/b"}
+	second := first.SetTest(tb)
+	assert.Panics(t, func() { second.Update([]byte("golden")) })
+	assert.Contains(t, tb.String(), "golden: the file testdata/collision-TestCollision-a-b.golden"+
+		" is also written by the test \"TestCollision/a-b\", the paths of the golden files of the tests collide")
+}
+
+type skipperTB struct {
+	bufferTB
+	skipped bool
+}
+
+func (s *skipperTB) SkipNow() {
+	s.skipped = true
+	panic("golden_test: method called SkipNow()")
+}
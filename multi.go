@@ -0,0 +1,87 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RunMulti is a functional that automates the process of reading the input
+// file of the test, the execution of the input function of testing that
+// produces several outputs and checking the results. See Tool.RunMulti.
+func RunMulti(t TestingTB, do func(input []byte) (outputs map[string][]byte, err error)) {
+	if h, ok := t.(testingHelper); ok {
+		h.Helper()
+	}
+	SetTest(t).RunMulti(do)
+}
+
+// RunMulti is a functional that automates the process of reading the input
+// file of the test, the execution of the input function of testing that
+// produces several outputs, for example the files of a code generator, and
+// checking the results. Each output is compared with its own golden file
+// with the escaped name of the output as the prefix, for example the output
+// "api/types.go" is compared with "testdata/TestFoo.api%2Ftypes.go.golden".
+// The golden file of the test, for example "testdata/TestFoo.golden",
+// contains the sorted list of the names of the outputs, so the test fails
+// if the set of the outputs changes. When the golden files are updated, the
+// golden files of the outputs that are no longer produced are deleted.
+func (t Tool) RunMulti(do func(input []byte) (outputs map[string][]byte, err error)) {
+	if h, ok := t.test.(testingHelper); ok {
+		h.Helper()
+	}
+
+	outputs, err := do(t.SetTarget(Input).Read())
+	t.noError(err)
+
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var list []byte
+	if len(names) != 0 {
+		list = []byte(strings.Join(names, "\n") + "\n")
+	}
+
+	if t.flag != nil && *t.flag && t.want == nil {
+		t.removeStaleOutputs(outputs)
+	}
+
+	cs := []Conclusion{t.Equal(list)}
+	for _, name := range names {
+		cs = append(cs, t.output(name).Equal(outputs[name]))
+	}
+	assertAll(t.test, cs...)
+}
+
+// removeStaleOutputs deletes the golden files of the outputs listed in the
+// golden file of the test that are no longer produced.
+func (t Tool) removeStaleOutputs(outputs map[string][]byte) {
+	for _, name := range strings.Split(string(t.SetTarget(Golden).Read()), "\n") {
+		if _, ok := outputs[name]; ok || name == "" {
+			continue
+		}
+
+		tool := t.output(name).SetTarget(Golden)
+		t.test.Logf("golden: the output %q is no longer produced, its golden file will be deleted", name)
+		tool.checkUpdateAllowed()
+		tool.write(nil)
+	}
+}
+
+// output returns the tool of the golden file of the output, the name of
+// the output is escaped because it may contain slashes and is joined with
+// the prefix of the test if it is set.
+func (t Tool) output(name string) Tool {
+	prefix := url.PathEscape(name)
+	if t.prefix != "" {
+		prefix = t.prefix + "." + prefix
+	}
+	return t.SetPrefix(prefix)
+}
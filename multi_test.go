@@ -0,0 +1,74 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTool_RunMulti(t *testing.T) {
+	outputs := map[string][]byte{
+		"api/types.go": []byte("package api"),
+		"README.md":    []byte("# API"),
+	}
+
+	t.Run("compare", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.golden"), []byte("README.md\napi/types.go\n"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.README.md.golden"), []byte("# API"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.api%2Ftypes.go.golden"), []byte("package api"), 0644))
+
+		tb := &bufferTB{name: "TestFoo"}
+		tool := _golden.SetTest(tb).SetStorage(s)
+		assert.NotPanics(t, func() {
+			tool.RunMulti(func([]byte) (map[string][]byte, error) { return outputs, nil })
+		})
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.golden"), []byte("README.md\napi/types.go\n"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.README.md.golden"), []byte("# Old"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.api%2Ftypes.go.golden"), []byte("package api"), 0644))
+
+		tb := &bufferTB{name: "TestFoo"}
+		tool := _golden.SetTest(tb).SetStorage(s)
+		assert.Panics(t, func() {
+			tool.RunMulti(func([]byte) (map[string][]byte, error) { return outputs, nil })
+		})
+		assert.Contains(t, tb.String(), "# Old")
+	})
+	t.Run("update", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.golden"), []byte("README.md\nstale.txt\n"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.README.md.golden"), []byte("# Old"), 0644))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.stale.txt.golden"), []byte("stale"), 0644))
+
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		tool.flag = &[]bool{true}[0]
+		tool.RunMulti(func([]byte) (map[string][]byte, error) { return outputs, nil })
+
+		for name, want := range map[string]string{
+			"TestFoo.golden":                "README.md\napi/types.go\n",
+			"TestFoo.README.md.golden":      "# API",
+			"TestFoo.api%2Ftypes.go.golden": "package api",
+		} {
+			bs, err := s.ReadFile(filepath.Join("testdata", name))
+			assert.NoError(t, err, name)
+			assert.Equal(t, want, string(bs), name)
+		}
+		_, err := s.Stat(filepath.Join("testdata", "TestFoo.stale.txt.golden"))
+		assert.Error(t, err)
+	})
+	t.Run("prefix", func(t *testing.T) {
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetPrefix("case")
+		assert.Equal(t, filepath.Join("testdata", "TestFoo.case.a%2Fb.golden"), tool.output("a/b").path())
+	})
+}
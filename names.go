@@ -0,0 +1,281 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// writtenPaths the names of the tests by the absolute paths of the files
+// written by them in the current run.
+var writtenPaths sync.Map
+
+// Portability defines how the names of files that cannot be created on all
+// operating systems are handled, for example names containing the ':'
+// character or reserved names like CON on Windows.
+type Portability uint
+
+const (
+	// PortabilityIgnore names are used as is.
+	PortabilityIgnore Portability = iota
+	// PortabilityCheck fails the test when a file with a non-portable name
+	// would be written.
+	PortabilityCheck
+	// PortabilityEscape non-portable characters and reserved names are
+	// reversibly escaped in the form %XX, the '%' character is also
+	// escaped, so the original name can always be restored by
+	// UnescapeName.
+	PortabilityEscape
+)
+
+// reservedNames the names of devices reserved on Windows, they cannot be
+// used as a file name even with an extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// name returns the name of the test or the name of the file if it is set
+// explicitly, the elements of the name are
+// escaped in the PortabilityEscape mode.
+func (t Tool) name() string {
+	name := t.test.Name()
+	if t.file != "" {
+		name = t.file
+	}
+	if t.portability != PortabilityEscape {
+		return name
+	}
+
+	elems := strings.Split(name, "/")
+	for i, elem := range elems {
+		elems[i] = escapeName(elem)
+	}
+
+	return strings.Join(elems, "/")
+}
+
+// escape escapes the element of the name in the PortabilityEscape mode.
+func (t Tool) escape(elem string) string {
+	if t.portability != PortabilityEscape {
+		return elem
+	}
+
+	return escapeName(elem)
+}
+
+// checkPortable fails the test if the path contains non-portable names.
+func (t Tool) checkPortable(path string) {
+	for _, elem := range strings.Split(filepath.ToSlash(path), "/") {
+		for i := 0; i < len(elem); i++ {
+			if !isPortable(elem, i) {
+				const f = "golden: the file name %q is not portable, it cannot be" +
+					" created on all operating systems, use SetPortability(PortabilityEscape)"
+				t.test.Fatalf(f, elem)
+			}
+		}
+	}
+}
+
+// checkCollision fails the test if the file was written in the current run
+// by another test, for example, if the names of the tests differ only in
+// characters that are replaced in file names, or by the path template.
+func (t Tool) checkCollision(path string) {
+	key := path
+	if abs, err := filepath.Abs(path); err == nil {
+		key = abs
+	}
+
+	name := t.test.Name()
+	if other, loaded := writtenPaths.LoadOrStore(key, name); loaded && other != name {
+		const f = "golden: the file %s is also written by the test %q," +
+			" the paths of the golden files of the tests collide"
+		t.test.Fatalf(f, path, other)
+	}
+}
+
+// escapeName escapes reversibly the characters not allowed in file names
+// on Windows, the trailing dots and spaces, and the reserved names.
+func escapeName(elem string) string {
+	buf := new(strings.Builder)
+	for i := 0; i < len(elem); i++ {
+		if c := elem[i]; c == '%' || !isPortable(elem, i) {
+			fmt.Fprintf(buf, "%%%02X", c)
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+
+	return buf.String()
+}
+
+// isPortable reports whether the i-th byte of the name is allowed in file
+// names on all operating systems.
+func isPortable(elem string, i int) bool {
+	c := elem[i]
+	switch {
+	case c < 0x20, c == 0x7f, strings.IndexByte(`<>:"\|?*`, c) >= 0:
+		return false
+	case i == len(elem)-1 && (c == '.' || c == ' ') && elem != "." && elem != "..":
+		return false
+	case i == 0 && isReserved(elem):
+		return false
+	}
+
+	return true
+}
+
+// UnescapeName restores the original name of the test from the element of
+// the name of the file escaped in the PortabilityEscape mode, for example
+// "key%3Avalue.golden" is restored to "key:value.golden".
+func UnescapeName(elem string) string {
+	buf := new(strings.Builder)
+	for i := 0; i < len(elem); i++ {
+		if elem[i] == '%' && i+2 < len(elem) {
+			if c, err := strconv.ParseUint(elem[i+1:i+3], 16, 8); err == nil {
+				buf.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		buf.WriteByte(elem[i])
+	}
+
+	return buf.String()
+}
+
+// isReserved reports whether the name before the first dot is a reserved
+// name on Windows.
+func isReserved(elem string) bool {
+	if i := strings.IndexByte(elem, '.'); i >= 0 {
+		elem = elem[:i]
+	}
+
+	return reservedNames[strings.ToUpper(strings.TrimRight(elem, " "))]
+}
+
+const (
+	// maxNameLength the maximum length in bytes of a file name supported
+	// by the most common file systems.
+	maxNameLength = 255
+	// maxExtLength the maximum length of the extension which is kept at
+	// the end of a shortened name.
+	maxExtLength = 32
+	// hashLength the length of the hash suffix of a shortened name.
+	hashLength = 16
+	// hashSeparator separates the truncated name from the hash suffix.
+	hashSeparator = "~"
+)
+
+// IndexFileName the name of the file, located in the directory of the
+// shortened files, that maps the hashes of shortened names back to the
+// full names of the tests and the full paths of the files, one line per
+// hash in the form "<hash> <test name> <full path>".
+const IndexFileName = "golden.index"
+
+// shorten truncates the elements of the path that are longer than
+// maxNameLength and adds a stable hash of the full element to them, the
+// extension of the element is kept. Also returns the full elements of the
+// shortened names by their hashes.
+func shorten(path string) (string, map[string]string) {
+	var names map[string]string
+	elems := strings.Split(path, string(filepath.Separator))
+	for i, elem := range elems {
+		if len(elem) <= maxNameLength {
+			continue
+		}
+
+		sum := sha256.Sum256([]byte(elem))
+		hash := hex.EncodeToString(sum[:])[:hashLength]
+		if names == nil {
+			names = make(map[string]string)
+		}
+		names[hash] = elem
+
+		ext := filepath.Ext(elem)
+		if len(ext) > maxExtLength {
+			ext = ""
+		}
+
+		head := elem[:maxNameLength-len(ext)-len(hashSeparator)-hashLength]
+		// Do not cut the multibyte character in half.
+		for !utf8.ValidString(head) {
+			head = head[:len(head)-1]
+		}
+
+		elems[i] = head + hashSeparator + hash + ext
+	}
+
+	return strings.Join(elems, string(filepath.Separator)), names
+}
+
+// index adds the full name of the test and the full path of the shortened
+// file to the index file next to it so that the files remain discoverable.
+// If the file is removed, then the hash of its name is removed from the
+// index, the hashes of the directories are kept for the other files, and
+// the index file without entries is removed.
+func (t Tool) index(path string, hashes map[string]string, removed bool) {
+	dir := filepath.Dir(path)
+	full := filepath.ToSlash(t.fullPath())
+	base := filepath.Base(t.fullPath())
+	path = filepath.Join(dir, IndexFileName)
+	t.mkdir(dir)
+	defer lockPath(dir)()
+
+	bs, err := t.readFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.noError(err)
+	}
+
+	entries := make(map[string]string, len(hashes))
+	for _, line := range strings.Split(string(bs), "\n") {
+		if fields := strings.SplitN(line, " ", 2); len(fields) == 2 {
+			entries[fields[0]] = fields[1]
+		}
+	}
+	for hash, name := range hashes {
+		switch {
+		case !removed:
+			entries[hash] = t.test.Name() + " " + full
+		case name == base:
+			delete(entries, hash)
+		}
+	}
+
+	keys := make([]string, 0, len(entries))
+	for hash := range entries {
+		keys = append(keys, hash)
+	}
+	sort.Strings(keys)
+
+	buf := new(bytes.Buffer)
+	for _, hash := range keys {
+		buf.WriteString(hash + " " + entries[hash] + "\n")
+	}
+	if bytes.Equal(bs, buf.Bytes()) {
+		return
+	}
+	if len(entries) == 0 {
+		t.test.Logf("golden: removing the empty index of shortened names: %s", path)
+		t.noError(t.remove(path))
+		return
+	}
+
+	t.test.Logf("golden: updating the index of shortened names: %s", path)
+	t.noError(t.writeFile(path, buf.Bytes(), t.fileMode))
+}
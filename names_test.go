@@ -0,0 +1,173 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_shorten(t *testing.T) {
+	long := strings.Repeat("long_subtest_name_", 20) + ".json.golden"
+
+	t.Run("short-path-unchanged", func(t *testing.T) {
+		path := filepath.Join("testdata", "TestFoo", "bar.golden")
+		got, names := shorten(path)
+		assert.Equal(t, path, got)
+		assert.Nil(t, names)
+	})
+	t.Run("long-element-shortened", func(t *testing.T) {
+		got, names := shorten(filepath.Join("testdata", "TestFoo", long))
+		elem := filepath.Base(got)
+		assert.Len(t, elem, maxNameLength)
+		assert.True(t, strings.HasSuffix(elem, ".golden"))
+		assert.Equal(t, filepath.Join("testdata", "TestFoo"), filepath.Dir(got))
+		if assert.Len(t, names, 1) {
+			for hash, name := range names {
+				assert.Equal(t, long, name)
+				assert.Contains(t, elem, hashSeparator+hash+".golden")
+			}
+		}
+	})
+	t.Run("stable", func(t *testing.T) {
+		first, _ := shorten(long)
+		second, _ := shorten(long)
+		other, _ := shorten("other" + long)
+		assert.Equal(t, first, second)
+		assert.NotEqual(t, first, other)
+	})
+	t.Run("multibyte-character-not-cut", func(t *testing.T) {
+		got, _ := shorten(strings.Repeat("ж", 200) + ".golden")
+		assert.True(t, utf8.ValidString(got))
+		assert.True(t, len(got) <= maxNameLength)
+	})
+}
+
+func TestTool_index(t *testing.T) {
+	name := "TestFoo/" + strings.Repeat("x", 300)
+	tool := _golden.SetTest(&bufferTB{name: name}).
+		SetPathTemplate("{{.Dir}}/golden/{{.Test}}{{.Prefix}}{{.Ext}}.{{.Target}}")
+	tool.flag = &[]bool{true}[0]
+
+	index := filepath.Join("testdata", "golden", "TestFoo", IndexFileName)
+	files := map[string][]byte{
+		index: []byte("0000000000000000 TestBar testdata/golden/TestBar.golden\n"),
+	}
+	tool.mkdirAll = func(path string, perm os.FileMode) error { return nil }
+	tool.stat = func(name string) (os.FileInfo, error) {
+		return &FakeStat{name: name, isDir: filepath.Ext(name) == ""}, nil
+	}
+	tool.readFile = func(filename string) ([]byte, error) {
+		if bs, ok := files[filename]; ok {
+			return bs, nil
+		}
+		return nil, os.ErrNotExist
+	}
+	tool.writeFile = func(filename string, data []byte, perm os.FileMode) error {
+		files[filename] = data
+		return nil
+	}
+	tool.remove = func(name string) error {
+		delete(files, name)
+		return nil
+	}
+
+	tool.Update([]byte("golden"))
+
+	path := tool.path()
+	assert.Equal(t, []byte("golden"), files[path])
+	assert.Equal(t, filepath.Dir(index), filepath.Dir(path))
+	_, hashes := shorten(tool.fullPath())
+	if assert.Len(t, hashes, 1) {
+		for hash := range hashes {
+			assert.Equal(t, "0000000000000000 TestBar testdata/golden/TestBar.golden\n"+
+				hash+" "+name+" testdata/golden/"+name+".golden\n",
+				string(files[index]))
+		}
+	}
+
+	tool.Update(nil)
+	assert.NotContains(t, files, path)
+	assert.Equal(t, "0000000000000000 TestBar testdata/golden/TestBar.golden\n", string(files[index]))
+
+	files[index] = nil
+	tool.Update([]byte("golden"))
+	tool.Update(nil)
+	assert.Equal(t, map[string][]byte{}, files)
+}
+
+func Test_escapeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "simple_name", want: "simple_name"},
+		{name: "key:value", want: "key%3Avalue"},
+		{name: `a*b?c<d>e|f"g\h`, want: "a%2Ab%3Fc%3Cd%3Ee%7Cf%22g%5Ch"},
+		{name: "100%", want: "100%25"},
+		{name: "CON", want: "%43ON"},
+		{name: "con.json", want: "%63on.json"},
+		{name: "COM1", want: "%43OM1"},
+		{name: "CONSOLE", want: "CONSOLE"},
+		{name: "trailing.", want: "trailing%2E"},
+		{name: "trailing ", want: "trailing%20"},
+		{name: "..", want: ".."},
+		{name: "ж", want: "ж"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, escapeName(tt.name))
+			assert.Equal(t, tt.name, UnescapeName(escapeName(tt.name)))
+		})
+	}
+}
+
+func TestUnescapeName(t *testing.T) {
+	assert.Equal(t, "key:value.golden", UnescapeName("key%3Avalue.golden"))
+	assert.Equal(t, "CON", UnescapeName("%43ON"))
+	assert.Equal(t, "100%", UnescapeName("100%"))
+	assert.Equal(t, "%zz", UnescapeName("%zz"))
+}
+
+func TestTool_SetPortability(t *testing.T) {
+	t.Run("escape", func(t *testing.T) {
+		tb := &bufferTB{name: "TestFoo/CON/key:value"}
+		tool := _golden.SetTest(tb).SetPrefix("a|b").SetPortability(PortabilityEscape)
+		assert.Equal(t, filepath.Join("testdata", "TestFoo", "%43ON", "key%3Avalue.a%7Cb.golden"), tool.path())
+
+		tool = tool.SetPathTemplate(ExtensionLastPathTemplate)
+		assert.Equal(t, filepath.Join("testdata", "TestFoo", "%43ON", "key%3Avalue.a%7Cb.golden"), tool.path())
+	})
+	t.Run("ignore", func(t *testing.T) {
+		tb := &bufferTB{name: "TestFoo/key:value"}
+		tool := _golden.SetTest(tb)
+		assert.Equal(t, filepath.Join("testdata", "TestFoo", "key:value.golden"), tool.path())
+	})
+	t.Run("check", func(t *testing.T) {
+		tb := &bufferTB{name: "TestFoo/key:value"}
+		tool := _golden.SetTest(tb).SetPortability(PortabilityCheck)
+		tool.writeFile = func(filename string, data []byte, perm os.FileMode) error {
+			t.Fatal("a file with a non-portable name should not be written")
+			return nil
+		}
+
+		assert.Panics(t, func() { tool.write([]byte("golden")) })
+		assert.Contains(t, tb.String(), `golden: the file name "key:value.golden" is not portable`)
+	})
+	t.Run("check-portable", func(t *testing.T) {
+		tb := &bufferTB{name: "TestFoo/100%"}
+		tool := _golden.SetTest(tb).SetPortability(PortabilityCheck)
+		tool.mkdirAll = func(path string, perm os.FileMode) error { return nil }
+		tool.stat = func(name string) (os.FileInfo, error) { return &FakeStat{isDir: true}, nil }
+		tool.writeFile = func(filename string, data []byte, perm os.FileMode) error { return nil }
+
+		assert.NotPanics(t, func() { tool.write([]byte("golden")) })
+	})
+}
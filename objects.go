@@ -0,0 +1,277 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// ObjectsDirName the default name of the directory in the directory for
+	// test data in which the object storage keeps the shared objects.
+	ObjectsDirName = ".golden-objects"
+	// objectRefPrefix the prefix of the content of a file that refers to
+	// the shared object, it is followed by the hex-encoded hash.
+	objectRefPrefix = "golden-object: sha256:"
+	// objectRefsName the name of the file in the objects directory that
+	// stores the hashes of the objects by the names of the files.
+	objectRefsName = "refs"
+)
+
+var (
+	_ Storage = new(objectStorage)
+	_ globber = new(objectStorage)
+)
+
+var errObjectCorrupted = errors.New("golden object is corrupted")
+
+// NewObjectStorage returns the content-addressed storage, in which the
+// content of each file is stored once as a shared object in the dir
+// directory of the s storage, for example "testdata/.golden-objects", and
+// the file itself contains a reference to the object:
+//
+//	golden-object: sha256:<hash>
+//
+// Files with identical content refer to the same object. The references
+// are tracked in the "refs" file of the dir directory, when the files are
+// written or removed, the objects of the directory that are not referenced
+// in it are removed, so the "refs" file must not be deleted by hand. If
+// the s storage does not support listing, only the object that the
+// changed file referred to is removed.
+func NewObjectStorage(s Storage, dir string) Storage {
+	dir = filepath.Clean(dir)
+	return &objectStorage{mu: objectsLock(dir), s: s, dir: dir}
+}
+
+// objectsLocks the locks of the objects directories by the absolute paths.
+var objectsLocks sync.Map
+
+// objectsLock returns the lock of the objects directory shared by all
+// object storages of the process, because the "refs" file and the objects
+// are shared by them.
+func objectsLock(dir string) *sync.Mutex {
+	key := dir
+	if abs, err := filepath.Abs(dir); err == nil {
+		key = abs
+	}
+
+	v, _ := objectsLocks.LoadOrStore(key, new(sync.Mutex))
+	return v.(*sync.Mutex)
+}
+
+type objectStorage struct {
+	// mu serializes changes of the references and the objects of the
+	// directory, it is shared by the storages of the same directory.
+	mu  *sync.Mutex
+	s   Storage
+	dir string
+}
+
+// Glob returns the names of the files matching the pattern, the objects
+// directory is not listed.
+func (o *objectStorage) Glob(pattern string) ([]string, error) {
+	g, ok := o.s.(globber)
+	if !ok {
+		return nil, errNoGlob
+	}
+
+	names, err := g.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := names[:0:0]
+	for _, name := range names {
+		if !isInside(o.dir, name) {
+			matches = append(matches, name)
+		}
+	}
+
+	return matches, nil
+}
+
+func (o *objectStorage) MkdirAll(path string, perm os.FileMode) error {
+	return o.s.MkdirAll(path, perm)
+}
+
+func (o *objectStorage) ReadFile(name string) ([]byte, error) {
+	bs, err := o.s.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, ok := parseObjectRef(bs)
+	if !ok {
+		return bs, nil
+	}
+
+	path := filepath.Join(o.dir, hash)
+	bs, err = o.s.ReadFile(path)
+	if os.IsNotExist(err) {
+		// The missing object is not a missing file, the reference is broken.
+		return nil, &os.PathError{Op: "read", Path: path, Err: errObjectCorrupted}
+	} else if err != nil {
+		return nil, err
+	}
+	if objectHash(bs) != hash {
+		return nil, &os.PathError{Op: "read", Path: path, Err: errObjectCorrupted}
+	}
+
+	return bs, nil
+}
+
+func (o *objectStorage) Remove(name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.s.Remove(name); err != nil {
+		return err
+	}
+
+	return o.setRef(name, "")
+}
+
+func (o *objectStorage) Stat(name string) (os.FileInfo, error) {
+	return o.s.Stat(name)
+}
+
+func (o *objectStorage) WriteFile(name string, data []byte, perm os.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	hash := objectHash(data)
+	path := filepath.Join(o.dir, hash)
+	if _, err := o.s.Stat(path); os.IsNotExist(err) {
+		if err := o.s.MkdirAll(o.dir, 0755); err != nil {
+			return err
+		}
+		if err := o.s.WriteFile(path, data, perm); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if err := o.s.WriteFile(name, []byte(objectRefPrefix+hash+"\n"), perm); err != nil {
+		return err
+	}
+
+	return o.setRef(name, hash)
+}
+
+// setRef sets the hash of the object to which the file refers, an empty
+// hash removes the reference, and removes the objects to which no file
+// refers anymore.
+func (o *objectStorage) setRef(name, hash string) error {
+	refsPath := filepath.Join(o.dir, objectRefsName)
+	bs, err := o.s.ReadFile(refsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	refs := make(map[string]string)
+	for _, line := range strings.Split(string(bs), "\n") {
+		if fields := strings.SplitN(line, " ", 2); len(fields) == 2 {
+			refs[fields[1]] = fields[0]
+		}
+	}
+
+	key := filepath.ToSlash(filepath.Clean(name))
+	old := refs[key]
+	if hash == "" {
+		delete(refs, key)
+	} else {
+		refs[key] = hash
+	}
+
+	names := make([]string, 0, len(refs))
+	used := make(map[string]bool, len(refs))
+	for name, hash := range refs {
+		names = append(names, name)
+		used[hash] = true
+	}
+	sort.Strings(names)
+
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		buf.WriteString(refs[name] + " " + name + "\n")
+	}
+	if !bytes.Equal(bs, buf.Bytes()) {
+		if err := o.s.WriteFile(refsPath, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+
+	return o.sweep(old, used)
+}
+
+// sweep removes the objects to which no file refers, all objects of the
+// directory are checked if the storage supports listing, otherwise only
+// the old object of the changed file is checked.
+func (o *objectStorage) sweep(old string, used map[string]bool) error {
+	var hashes []string
+	if g, ok := o.s.(globber); ok {
+		names, err := g.Glob(filepath.Join(o.dir, "*"))
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			hashes = append(hashes, filepath.Base(name))
+		}
+	} else if old != "" {
+		hashes = append(hashes, old)
+	}
+
+	for _, hash := range hashes {
+		if used[hash] || !isObjectHash(hash) {
+			continue
+		}
+		err := o.s.Remove(filepath.Join(o.dir, hash))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseObjectRef returns the hash of the object if the content of the file
+// is a reference to the object.
+func parseObjectRef(bs []byte) (hash string, ok bool) {
+	s := strings.TrimSuffix(string(bs), "\n")
+	if !strings.HasPrefix(s, objectRefPrefix) {
+		return "", false
+	}
+
+	hash = strings.TrimPrefix(s, objectRefPrefix)
+	return hash, isObjectHash(hash)
+}
+
+// isObjectHash reports whether the name is the hex-encoded hash of an
+// object.
+func isObjectHash(name string) bool {
+	_, err := hex.DecodeString(name)
+	return err == nil && len(name) == 2*sha256.Size
+}
+
+// objectHash returns the hex-encoded hash of the content of the object.
+func objectHash(bs []byte) string {
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:])
+}
+
+// isInside reports whether the path is the dir directory or is inside it.
+func isInside(dir, path string) bool {
+	rel, err := filepath.Rel(dir, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewObjectStorage(t *testing.T) {
+	dir := filepath.Join("testdata", ObjectsDirName)
+	first := filepath.Join("testdata", "TestFoo", "first.golden")
+	second := filepath.Join("testdata", "TestFoo", "second.golden")
+	hash := objectHash([]byte("golden"))
+
+	t.Run("deduplication", func(t *testing.T) {
+		base := NewMemoryStorage()
+		s := NewObjectStorage(base, dir)
+		assert.NoError(t, s.MkdirAll(filepath.Dir(first), 0755))
+		assert.NoError(t, s.WriteFile(first, []byte("golden"), 0644))
+		assert.NoError(t, s.WriteFile(second, []byte("golden"), 0644))
+
+		ref, err := base.ReadFile(first)
+		assert.NoError(t, err)
+		assert.Equal(t, objectRefPrefix+hash+"\n", string(ref))
+
+		for _, name := range []string{first, second} {
+			bs, err := s.ReadFile(name)
+			assert.NoError(t, err)
+			assert.Equal(t, "golden", string(bs))
+		}
+
+		refs, err := base.ReadFile(filepath.Join(dir, objectRefsName))
+		assert.NoError(t, err)
+		assert.Equal(t, hash+" testdata/TestFoo/first.golden\n"+
+			hash+" testdata/TestFoo/second.golden\n", string(refs))
+	})
+	t.Run("garbage-collection", func(t *testing.T) {
+		base := NewMemoryStorage()
+		s := NewObjectStorage(base, dir)
+		assert.NoError(t, s.MkdirAll(filepath.Dir(first), 0755))
+		assert.NoError(t, s.WriteFile(first, []byte("golden"), 0644))
+		assert.NoError(t, s.WriteFile(second, []byte("golden"), 0644))
+
+		assert.NoError(t, s.WriteFile(first, []byte("other"), 0644))
+		_, err := base.Stat(filepath.Join(dir, hash))
+		assert.NoError(t, err, "the object is still referenced by the second file")
+
+		assert.NoError(t, s.Remove(second))
+		_, err = base.Stat(filepath.Join(dir, hash))
+		assert.True(t, os.IsNotExist(err), "the unreferenced object must be removed")
+
+		bs, err := s.ReadFile(first)
+		assert.NoError(t, err)
+		assert.Equal(t, "other", string(bs))
+	})
+	t.Run("unreferenced-objects-swept", func(t *testing.T) {
+		stray := filepath.Join(dir, objectHash([]byte("stray")))
+
+		base := NewMemoryStorage()
+		assert.NoError(t, base.MkdirAll(dir, 0755))
+		assert.NoError(t, base.WriteFile(stray, []byte("stray"), 0644))
+		s := NewObjectStorage(base, dir)
+		assert.NoError(t, s.MkdirAll(filepath.Dir(first), 0755))
+		assert.NoError(t, s.WriteFile(first, []byte("golden"), 0644))
+
+		_, err := base.Stat(stray)
+		assert.True(t, os.IsNotExist(err), "the unreferenced object must be removed")
+		_, err = base.Stat(filepath.Join(dir, objectRefsName))
+		assert.NoError(t, err)
+
+		base = NewMemoryStorage()
+		assert.NoError(t, base.MkdirAll(dir, 0755))
+		assert.NoError(t, base.WriteFile(stray, []byte("stray"), 0644))
+		s = NewObjectStorage(struct{ Storage }{base}, dir)
+		assert.NoError(t, s.MkdirAll(filepath.Dir(first), 0755))
+		assert.NoError(t, s.WriteFile(first, []byte("golden"), 0644))
+
+		_, err = base.Stat(stray)
+		assert.NoError(t, err, "the objects are not listed without the support of listing")
+	})
+	t.Run("plain-file", func(t *testing.T) {
+		base := NewMemoryStorage()
+		assert.NoError(t, base.WriteFile("file", []byte("golden"), 0644))
+		bs, err := NewObjectStorage(base, dir).ReadFile("file")
+		assert.NoError(t, err)
+		assert.Equal(t, "golden", string(bs))
+	})
+	t.Run("corrupted-object", func(t *testing.T) {
+		base := NewMemoryStorage()
+		s := NewObjectStorage(base, dir)
+		assert.NoError(t, s.MkdirAll(filepath.Dir(first), 0755))
+		assert.NoError(t, s.WriteFile(first, []byte("golden"), 0644))
+		assert.NoError(t, base.WriteFile(filepath.Join(dir, hash), []byte("edited"), 0644))
+
+		_, err := s.ReadFile(first)
+		assert.EqualError(t, err, "read "+filepath.Join(dir, hash)+": golden object is corrupted")
+		assert.False(t, os.IsNotExist(err))
+	})
+	t.Run("tool", func(t *testing.T) {
+		tool := _golden.SetTest(&bufferTB{name: t.Name()}).SetStorage(NewObjectStorage(NewMemoryStorage(), dir))
+		tool.flag = &[]bool{true}[0]
+		tool.Assert([]byte("golden"))
+		tool.flag = nil
+		assert.False(t, tool.Equal([]byte("golden")).Failed())
+	})
+}
+
+func TestObjectStorage_Glob(t *testing.T) {
+	s := NewObjectStorage(NewMemoryStorage(), filepath.Join("testdata", ObjectsDirName))
+	assert.NoError(t, s.MkdirAll("testdata", 0755))
+	assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.golden"), []byte("golden"), 0644))
+
+	matches, err := s.(globber).Glob(filepath.Join("testdata", "*"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join("testdata", "TestFoo.golden")}, matches)
+}
+
+// yieldingStorage yields the processor on reading, so that the concurrent
+// changes are interleaved.
+type yieldingStorage struct {
+	*MemoryStorage
+}
+
+func (y yieldingStorage) ReadFile(name string) ([]byte, error) {
+	runtime.Gosched()
+	return y.MemoryStorage.ReadFile(name)
+}
+
+func TestObjectStorage_parallel(t *testing.T) {
+	dir := filepath.Join("testdata", ObjectsDirName)
+	base := yieldingStorage{NewMemoryStorage()}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := NewObjectStorage(base, dir)
+			name := filepath.Join("testdata", fmt.Sprintf("Test%d", i), "golden")
+			assert.NoError(t, s.MkdirAll(filepath.Dir(name), 0755))
+			for j := 0; j < 10; j++ {
+				assert.NoError(t, s.WriteFile(name, []byte(fmt.Sprint("golden ", i, j)), 0644))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	s := NewObjectStorage(base, dir)
+	for i := 0; i < 64; i++ {
+		bs, err := s.ReadFile(filepath.Join("testdata", fmt.Sprintf("Test%d", i), "golden"))
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprint("golden ", i, 9), string(bs))
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/xorcare/golden/internal/txtar"
+)
+
+// ScriptSection the name of the section of the script with the expected
+// transcript of the commands.
+const ScriptSection = "golden"
+
+// Script runs the script of commands and compares the transcript with the
+// expected one. See Tool.Script.
+func Script(t TestingTB, path string) {
+	if h, ok := t.(testingHelper); ok {
+		h.Helper()
+	}
+	SetTest(t).Script(path)
+}
+
+// Script runs the script of commands stored in the txtar archive and
+// compares the transcript with the expected one, for example:
+//
+//	# the commands, one per line, the lines starting with # are ignored
+//	mytool -format=json input.txt
+//	-- input.txt --
+//	hello
+//	-- golden --
+//	$ mytool -format=json input.txt
+//	{"text": "hello"}
+//
+// The files of the archive are written to a temporary directory, then the
+// commands from the comment of the archive are run in it one by one. The
+// arguments of the commands are separated by spaces and can be quoted by
+// single or double quotes. The transcript consists of each command
+// prefixed by "$ ", its combined standard output and standard error
+// terminated by a new line and its exit status if it is not zero. The
+// working directory and the temporary directory are replaced in the
+// transcript by $WORK and $TMPDIR.
+// The transcript is compared with the section named golden, when the
+// golden files are updated, the section is rewritten in place.
+func (t Tool) Script(path string) {
+	if h, ok := t.test.(testingHelper); ok {
+		h.Helper()
+	}
+
+	bs, err := t.readFile(path)
+	t.noError(err)
+	ar := txtar.Parse(bs)
+
+	dir, err := os.MkdirTemp("", "golden-script-")
+	t.noError(err)
+	defer os.RemoveAll(dir)
+
+	want := []byte{}
+	for _, f := range ar.Files {
+		if f.Name == ScriptSection {
+			want = f.Data
+			continue
+		}
+		t.writeScriptFile(dir, f)
+	}
+
+	got := t.runScript(dir, ar.Comment)
+	if len(got) == 0 {
+		t.test.Fatalf("golden: the script %s has no commands", path)
+	}
+	if t.flag != nil && *t.flag && t.want == nil {
+		t.updateScript(path, ar, got)
+		return
+	}
+
+	t.SetWant(want).Equal(got).FailNow()
+}
+
+// writeScriptFile writes the file of the script to the directory.
+func (t Tool) writeScriptFile(dir string, f txtar.File) {
+	name := filepath.Clean(filepath.FromSlash(f.Name))
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		t.test.Fatalf("golden: the name of the file %q of the script is not local", f.Name)
+	}
+
+	path := filepath.Join(dir, name)
+	t.noError(os.MkdirAll(filepath.Dir(path), t.modeDir))
+	t.noError(os.WriteFile(path, f.Data, t.fileMode))
+}
+
+// runScript runs the commands in the directory and returns the transcript.
+func (t Tool) runScript(dir string, commands []byte) []byte {
+	scrub := newScrubber(dir)
+	transcript := new(bytes.Buffer)
+	for _, line := range strings.Split(string(commands), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		args, err := splitArgs(line)
+		t.noError(err)
+
+		out := new(bytes.Buffer)
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = dir
+		cmd.Stdout, cmd.Stderr = out, out
+
+		t.test.Logf("golden: running the command of the script: %s", line)
+		code := 0
+		var exitErr *exec.ExitError
+		if err := cmd.Run(); errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		} else {
+			t.noError(err)
+		}
+
+		fmt.Fprintf(transcript, "$ %s\n", line)
+		output := scrub(out.Bytes())
+		transcript.Write(output)
+		if len(output) != 0 && output[len(output)-1] != '\n' {
+			transcript.WriteByte('\n')
+		}
+		if code != 0 {
+			fmt.Fprintf(transcript, "[exit status %d]\n", code)
+		}
+	}
+
+	return transcript.Bytes()
+}
+
+// updateScript rewrites the section of the script with the transcript.
+func (t Tool) updateScript(path string, ar *txtar.Archive, transcript []byte) {
+	if txtar.HasMarker(transcript) {
+		t.test.Fatalf("golden: the transcript of the script %s contains a line like a file marker", path)
+	}
+
+	t.checkUpdateAllowed()
+	printUpdateBanner()
+	t.test.Logf("golden: updating file: %s", path)
+
+	found := false
+	for i := range ar.Files {
+		if ar.Files[i].Name == ScriptSection {
+			ar.Files[i].Data, found = transcript, true
+		}
+	}
+	if !found {
+		ar.Files = append(ar.Files, txtar.File{Name: ScriptSection, Data: transcript})
+	}
+	t.put(path, txtar.Format(ar))
+}
+
+// splitArgs splits the command line into the arguments separated by spaces,
+// the arguments can be quoted by single or double quotes.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("golden: unterminated quote in the command: %s", line)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
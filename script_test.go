@@ -0,0 +1,137 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestScriptHelperProcess is not a real test, it is the process run by the
+// tests of Script, it prints the arguments without the trailing new line.
+func TestScriptHelperProcess(t *testing.T) {
+	if os.Getenv("GOLDEN_EXEC_HELPER_PROCESS") != "1" {
+		t.Skip("it is the helper process of the tests of Script")
+	}
+	fmt.Fprint(os.Stdout, flag.Args())
+	os.Exit(0)
+}
+
+func TestTool_Script(t *testing.T) {
+	t.Setenv("GOLDEN_EXEC_HELPER_PROCESS", "1")
+	line := "'" + os.Args[0] + "' -test.run=^TestExecHelperProcess$"
+	path := filepath.Join("testdata", "TestFoo.txtar")
+	script := "# runs the helper process\n" + line + "\n" +
+		"-- input.txt --\n" +
+		"hello\n"
+	transcript := "$ " + line + "\n" +
+		"work: $WORK\n" +
+		"hello\n" +
+		"temp: $TMPDIR" + string(filepath.Separator) + "x\n" +
+		"[exit status 3]\n"
+
+	t.Run("update", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(path, []byte(script), 0644))
+
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		tool.flag = &[]bool{true}[0]
+		tool.Script(path)
+
+		bs, err := s.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, script+"-- golden --\n"+transcript, string(bs))
+
+		tool = tool.SetTest(&bufferTB{name: "TestFoo"})
+		tool.flag = nil
+		assert.NotPanics(t, func() { tool.Script(path) })
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(path, []byte(script+"-- golden --\n$ old\n"), 0644))
+
+		tb := &bufferTB{name: "TestFoo"}
+		tool := _golden.SetTest(tb).SetStorage(s)
+		assert.Panics(t, func() { tool.Script(path) })
+		assert.Contains(t, tb.String(), "-$ old")
+	})
+	t.Run("no-trailing-new-line", func(t *testing.T) {
+		line := "'" + os.Args[0] + "' -test.run=^TestScriptHelperProcess$ -- x"
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(path, []byte(line+"\n"), 0644))
+
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		tool.flag = &[]bool{true}[0]
+		tool.Script(path)
+
+		bs, err := s.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, line+"\n-- golden --\n$ "+line+"\n[x]\n", string(bs))
+
+		tool = tool.SetTest(&bufferTB{name: "TestFoo"})
+		tool.flag = nil
+		assert.NotPanics(t, func() { tool.Script(path) })
+	})
+	t.Run("fallback-file-kept", func(t *testing.T) {
+		fallback := filepath.Join("testdata", "TestFoo.json.golden")
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(path, []byte(script), 0644))
+		assert.NoError(t, s.WriteFile(fallback, []byte("golden"), 0644))
+
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s).
+			SetPathTemplate(ExtensionLastPathTemplate).
+			SetFallbackPathTemplate(DefaultPathTemplate).
+			setExtension("json")
+		tool.flag = &[]bool{true}[0]
+		tool.Script(path)
+
+		bs, err := s.ReadFile(fallback)
+		assert.NoError(t, err)
+		assert.Equal(t, "golden", string(bs))
+	})
+	t.Run("not-local-file", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(path, []byte(line+"\n-- ../x --\n"), 0644))
+
+		tb := &bufferTB{name: "TestFoo"}
+		assert.Panics(t, func() { _golden.SetTest(tb).SetStorage(s).Script(path) })
+		assert.Contains(t, tb.String(), `the name of the file "../x" of the script is not local`)
+	})
+}
+
+func Test_splitArgs(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "echo", want: []string{"echo"}},
+		{line: "echo  a\tb", want: []string{"echo", "a", "b"}},
+		{line: `echo 'a b' "c 'd'" e""`, want: []string{"echo", "a b", "c 'd'", "e"}},
+		{line: `echo ''`, want: []string{"echo", ""}},
+		{line: `echo 'a`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := splitArgs(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
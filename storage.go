@@ -0,0 +1,343 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errNoGlob the error of listing the files of the storage that does not
+// support it.
+var errNoGlob = errors.New("golden: the storage does not support listing files")
+
+// Storage is the interface of the storage of golden and input files. The
+// methods have the same semantics as the functions of the same name from
+// the os package, errors about missing files must satisfy os.IsNotExist.
+type Storage interface {
+	MkdirAll(path string, perm os.FileMode) error
+	ReadFile(name string) ([]byte, error)
+	Remove(name string) error
+	Stat(name string) (os.FileInfo, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+}
+
+// globber the interface of the storage that can list files matching the
+// pattern, the pattern has the syntax of filepath.Match.
+type globber interface {
+	Glob(pattern string) ([]string, error)
+}
+
+var (
+	_ globber = osStorage{}
+	_ globber = new(MemoryStorage)
+	_ globber = readOnlyStorage{}
+)
+
+var (
+	_ Storage = osStorage{}
+	_ Storage = new(MemoryStorage)
+	_ Storage = readOnlyStorage{}
+)
+
+// SetStorage a storage setter, all files are read and written through the
+// storage. The functions that list the files, like RunDir, are supported if
+// the storage has the method Glob with the semantics of filepath.Glob. A
+// nil value resets the storage to the file system of the operating system.
+func (t Tool) SetStorage(s Storage) Tool {
+	if s == nil {
+		s = NewOSStorage()
+	}
+
+	t.mkdirAll = s.MkdirAll
+	t.readFile = s.ReadFile
+	t.remove = s.Remove
+	t.stat = s.Stat
+	t.writeFile = s.WriteFile
+	t.readFS = false
+	t.glob = nil
+	if g, ok := s.(globber); ok {
+		t.glob = g.Glob
+	}
+
+	return t
+}
+
+// NewOSStorage returns the storage in the file system of the operating
+// system, it is used by default.
+func NewOSStorage() Storage {
+	return osStorage{}
+}
+
+type osStorage struct{}
+
+func (osStorage) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (osStorage) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+func (osStorage) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osStorage) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osStorage) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return writeFileAtomic(name, data, perm)
+}
+
+func (osStorage) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it to the named file, so an interrupted write never leaves the
+// file truncated. The file gets the perm permissions even if it exists.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(name), ".golden-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), name)
+}
+
+// NewReadOnlyStorage returns the storage that reads files from the s
+// storage, and any attempt to change it fails with an error satisfying
+// os.IsPermission.
+func NewReadOnlyStorage(s Storage) Storage {
+	return readOnlyStorage{s: s}
+}
+
+type readOnlyStorage struct {
+	s Storage
+}
+
+func (r readOnlyStorage) MkdirAll(path string, _ os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: path, Err: os.ErrPermission}
+}
+
+func (r readOnlyStorage) ReadFile(name string) ([]byte, error) {
+	return r.s.ReadFile(name)
+}
+
+func (r readOnlyStorage) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+}
+
+func (r readOnlyStorage) Stat(name string) (os.FileInfo, error) {
+	return r.s.Stat(name)
+}
+
+func (r readOnlyStorage) WriteFile(name string, _ []byte, _ os.FileMode) error {
+	return &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+}
+
+func (r readOnlyStorage) Glob(pattern string) ([]string, error) {
+	g, ok := r.s.(globber)
+	if !ok {
+		return nil, errNoGlob
+	}
+
+	return g.Glob(pattern)
+}
+
+// MemoryStorage is the storage that keeps files in memory, it is intended
+// for testing without touching the disk. It is safe for concurrent use.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	files map[string]memoryFile
+	dirs  map[string]os.FileMode
+}
+
+type memoryFile struct {
+	data []byte
+	mode os.FileMode
+}
+
+// NewMemoryStorage returns an empty storage in memory.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		files: make(map[string]memoryFile),
+		dirs:  make(map[string]os.FileMode),
+	}
+}
+
+// MkdirAll creates a directory named path, along with any necessary parents.
+func (m *MemoryStorage) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for path = filepath.Clean(path); !m.isRoot(path); path = filepath.Dir(path) {
+		if _, ok := m.files[path]; ok {
+			return &os.PathError{Op: "mkdir", Path: path, Err: os.ErrExist}
+		}
+		if _, ok := m.dirs[path]; !ok {
+			m.dirs[path] = perm
+		}
+	}
+
+	return nil
+}
+
+// ReadFile reads the file named by name and returns the contents.
+func (m *MemoryStorage) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	bs := make([]byte, len(f.data))
+	copy(bs, f.data)
+
+	return bs, nil
+}
+
+// Remove removes the named file or empty directory.
+func (m *MemoryStorage) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := filepath.Clean(name)
+	if _, ok := m.files[path]; ok {
+		delete(m.files, path)
+		return nil
+	}
+	if _, ok := m.dirs[path]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	for p := range m.files {
+		if filepath.Dir(p) == path {
+			return &os.PathError{Op: "remove", Path: name, Err: os.ErrExist}
+		}
+	}
+	for p := range m.dirs {
+		if filepath.Dir(p) == path {
+			return &os.PathError{Op: "remove", Path: name, Err: os.ErrExist}
+		}
+	}
+	delete(m.dirs, path)
+
+	return nil
+}
+
+// Stat returns a os.FileInfo describing the named file.
+func (m *MemoryStorage) Stat(name string) (os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	path := filepath.Clean(name)
+	if f, ok := m.files[path]; ok {
+		return memoryFileInfo{name: filepath.Base(path), size: len(f.data), mode: f.mode}, nil
+	}
+	if perm, ok := m.dirs[path]; ok {
+		return memoryFileInfo{name: filepath.Base(path), mode: os.ModeDir | perm}, nil
+	}
+	if m.isRoot(path) {
+		return memoryFileInfo{name: path, mode: os.ModeDir | 0755}, nil
+	}
+
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+// WriteFile writes data to the file named by name, the directory of the
+// file must exist.
+func (m *MemoryStorage) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := filepath.Clean(name)
+	if _, ok := m.dirs[path]; ok {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	}
+	if dir := filepath.Dir(path); !m.isRoot(dir) {
+		if _, ok := m.dirs[dir]; !ok {
+			return &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+	}
+
+	f := memoryFile{data: make([]byte, len(data)), mode: perm}
+	if old, ok := m.files[path]; ok {
+		// As in the os package, the permissions of an existing file
+		// are not changed.
+		f.mode = old.mode
+	}
+	copy(f.data, data)
+	m.files[path] = f
+
+	return nil
+}
+
+// Glob returns the names of all files matching the pattern, the pattern
+// has the syntax of filepath.Match.
+func (m *MemoryStorage) Glob(pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var matches []string
+	for name := range m.files {
+		if ok, _ := filepath.Match(filepath.Clean(pattern), name); ok {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
+// isRoot reports whether the path is the root of the file system or the
+// current directory, which always exist.
+func (m *MemoryStorage) isRoot(path string) bool {
+	return path == "." || path == filepath.Dir(path)
+}
+
+type memoryFileInfo struct {
+	name string
+	size int
+	mode os.FileMode
+}
+
+func (i memoryFileInfo) Name() string       { return i.name }
+func (i memoryFileInfo) Size() int64        { return int64(i.size) }
+func (i memoryFileInfo) Mode() os.FileMode  { return i.mode }
+func (i memoryFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memoryFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memoryFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,154 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTool_SetStorage(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tb := &bufferTB{name: t.Name()}
+		tool := _golden.SetTest(tb).SetStorage(s)
+		tool.flag = &[]bool{true}[0]
+
+		assert.False(t, tool.Equal([]byte("golden")).Failed())
+
+		bs, err := s.ReadFile(filepath.Join("testdata", "TestTool_SetStorage", "memory.golden"))
+		assert.NoError(t, err)
+		assert.Equal(t, "golden", string(bs))
+
+		tool.flag = nil
+		assert.True(t, tool.Equal([]byte("Z29sZGVu")).Failed())
+	})
+	t.Run("read-only", func(t *testing.T) {
+		s := NewMemoryStorage()
+		name := filepath.Join("testdata", "TestTool_SetStorage", "read-only.golden")
+		assert.NoError(t, s.MkdirAll(filepath.Dir(name), 0755))
+		assert.NoError(t, s.WriteFile(name, []byte("golden"), 0644))
+
+		tb := &bufferTB{name: t.Name()}
+		tool := _golden.SetTest(tb).SetStorage(NewReadOnlyStorage(s))
+		assert.Equal(t, "golden", string(tool.Read()))
+
+		tool.flag = &[]bool{true}[0]
+		assert.Panics(t, func() { tool.Update([]byte("golden")) })
+		assert.Contains(t, tb.String(), "permission denied")
+	})
+	t.Run("reset", func(t *testing.T) {
+		tb := &bufferTB{name: t.Name()}
+		tool := _golden.SetTest(tb).SetStorage(NewMemoryStorage()).SetStorage(nil)
+		_, err := tool.stat(".")
+		assert.NoError(t, err)
+	})
+}
+
+func TestMemoryStorage(t *testing.T) {
+	s := NewMemoryStorage()
+	name := filepath.Join("testdata", "dir", "file.golden")
+
+	_, err := s.ReadFile(name)
+	assert.True(t, os.IsNotExist(err))
+	assert.True(t, os.IsNotExist(s.WriteFile(name, []byte("golden"), 0644)))
+
+	assert.NoError(t, s.MkdirAll(filepath.Dir(name), 0755))
+	assert.NoError(t, s.WriteFile(name, []byte("golden"), 0644))
+
+	bs, err := s.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "golden", string(bs))
+
+	fi, err := s.Stat(name)
+	assert.NoError(t, err)
+	assert.False(t, fi.IsDir())
+	assert.Equal(t, int64(6), fi.Size())
+	assert.Equal(t, "file.golden", fi.Name())
+
+	fi, err = s.Stat("testdata")
+	assert.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	assert.Error(t, s.Remove(filepath.Dir(name)), "non-empty directory must not be removed")
+	assert.Error(t, s.MkdirAll(filepath.Join(name, "sub"), 0755), "file must not be a directory")
+
+	assert.NoError(t, s.Remove(name))
+	assert.True(t, os.IsNotExist(s.Remove(name)))
+	assert.NoError(t, s.Remove(filepath.Dir(name)))
+
+	_, err = s.Stat(name)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadOnlyStorage(t *testing.T) {
+	s := NewReadOnlyStorage(NewMemoryStorage())
+	assert.True(t, os.IsPermission(s.MkdirAll("testdata", 0755)))
+	assert.True(t, os.IsPermission(s.WriteFile("file", nil, 0644)))
+	assert.True(t, os.IsPermission(s.Remove("file")))
+
+	_, err := s.ReadFile("file")
+	assert.True(t, os.IsNotExist(err))
+	_, err = s.Stat("file")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestOSStorage(t *testing.T) {
+	s := NewOSStorage()
+	name := filepath.Join(t.TempDir(), "dir", "file.golden")
+
+	assert.NoError(t, s.MkdirAll(filepath.Dir(name), 0755))
+	assert.NoError(t, s.WriteFile(name, []byte("golden"), 0644))
+
+	bs, err := s.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "golden", string(bs))
+
+	fi, err := s.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), fi.Size())
+
+	assert.NoError(t, s.Remove(name))
+	_, err = s.ReadFile(name)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_writeFileAtomic(t *testing.T) {
+	t.Run("write", func(t *testing.T) {
+		dir := t.TempDir()
+		name := filepath.Join(dir, "file.golden")
+		assert.NoError(t, writeFileAtomic(name, []byte("first"), 0600))
+		assert.NoError(t, writeFileAtomic(name, []byte("golden"), 0644))
+
+		bs, err := ioutil.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "golden", string(bs))
+
+		if runtime.GOOS != "windows" {
+			fi, err := os.Stat(name)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0644), fi.Mode().Perm())
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1, "temporary files must not be left")
+	})
+	t.Run("error", func(t *testing.T) {
+		dir := t.TempDir()
+		name := filepath.Join(dir, "file.golden")
+		assert.NoError(t, os.Mkdir(name, 0755))
+		assert.Error(t, writeFileAtomic(name, []byte("golden"), 0644))
+
+		files, err := ioutil.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1, "temporary files must not be left")
+	})
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SumFileName the conventional name of the checksum manifest in the
+// directory for test data, see SetSumFile.
+const SumFileName = "golden.sum"
+
+// sumHashPrefix the prefix of the hashes in the checksum manifest.
+const sumHashPrefix = "sha256:"
+
+// sumEntry the entry of the checksum manifest.
+type sumEntry struct {
+	hash string
+	test string
+}
+
+// SetSumFile a setter of the checksum manifest file, for example
+// "testdata/golden.sum". The manifest is maintained when the golden files
+// are updated, it records the hash of each golden file and the name of
+// the test that produced it:
+//
+//	testdata/TestFoo.golden sha256:<hash> TestFoo
+//
+// When the golden file is read, its hash is verified against the
+// manifest, and the test fails if the file was edited by hand after
+// generation, corrupted, or is not recorded in the manifest. The files
+// read through the fallback path template are verified by their own path.
+// Input files are neither recorded nor verified. An empty name disables
+// the manifest.
+func (t Tool) SetSumFile(name string) Tool {
+	t.sum = name
+	return t
+}
+
+// readSum reads the entries of the checksum manifest.
+func (t Tool) readSum() (map[string]sumEntry, []byte) {
+	bs, err := t.readFile(t.sum)
+	if err != nil && !os.IsNotExist(err) {
+		t.noError(err)
+	}
+
+	entries := make(map[string]sumEntry)
+	for _, line := range strings.Split(string(bs), "\n") {
+		if fields := strings.SplitN(line, " ", 3); len(fields) == 3 {
+			entries[fields[0]] = sumEntry{hash: fields[1], test: fields[2]}
+		}
+	}
+
+	return entries, bs
+}
+
+// updateSum records the hash of the data of the file in the checksum
+// manifest, the nil data removes the file from the manifest.
+func (t Tool) updateSum(path string, data []byte) {
+	t.mkdir(filepath.Dir(t.sum))
+	defer lockPath(filepath.Dir(t.sum))()
+
+	entries, bs := t.readSum()
+	key := filepath.ToSlash(path)
+	if data == nil {
+		delete(entries, key)
+	} else {
+		entries[key] = sumEntry{hash: sumHash(data), test: t.test.Name()}
+	}
+
+	paths := make([]string, 0, len(entries))
+	for path := range entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	buf := new(bytes.Buffer)
+	for _, path := range paths {
+		buf.WriteString(path + " " + entries[path].hash + " " + entries[path].test + "\n")
+	}
+	if bytes.Equal(bs, buf.Bytes()) {
+		return
+	}
+
+	t.test.Logf("golden: updating the checksum manifest: %s", t.sum)
+	t.noError(t.writeFile(t.sum, buf.Bytes(), t.fileMode))
+}
+
+// verifySum fails the test if the hash of the data of the file does not
+// match the checksum manifest.
+func (t Tool) verifySum(path string, data []byte) {
+	entries, _ := t.readSum()
+	entry, ok := entries[filepath.ToSlash(path)]
+	switch {
+	case !ok:
+		const f = "golden: the file %s is not recorded in the checksum manifest %s," +
+			" it was not produced by the update of the golden files"
+		t.test.Errorf(f, path, t.sum)
+	case entry.hash != sumHash(data):
+		const f = "golden: the file %s does not match the checksum manifest %s," +
+			" it was edited or corrupted after it was produced by the test %s"
+		t.test.Errorf(f, path, t.sum, entry.test)
+	}
+}
+
+// sumHash returns the hash of the data in the format of the manifest.
+func sumHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return sumHashPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTool_SetSumFile(t *testing.T) {
+	sumFile := filepath.Join("testdata", SumFileName)
+	path := filepath.Join("testdata", "TestFoo", "sub.golden")
+
+	newTool := func(s Storage) (Tool, *bufferTB) {
+		tb := &bufferTB{name: "TestFoo/sub"}
+		return _golden.SetTest(tb).SetStorage(s).SetSumFile(sumFile), tb
+	}
+
+	t.Run("update-maintains-manifest", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tool, _ := newTool(s)
+		tool.flag = &[]bool{true}[0]
+		tool.Update([]byte("golden"))
+
+		bs, err := s.ReadFile(sumFile)
+		assert.NoError(t, err)
+		assert.Equal(t, "testdata/TestFoo/sub.golden "+sumHash([]byte("golden"))+" TestFoo/sub\n", string(bs))
+
+		tool.Update(nil)
+		bs, err = s.ReadFile(sumFile)
+		assert.NoError(t, err)
+		assert.Empty(t, string(bs))
+	})
+	t.Run("verified", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tool, tb := newTool(s)
+		tool.flag = &[]bool{true}[0]
+		tool.Update([]byte("golden"))
+
+		tool.flag = nil
+		assert.False(t, tool.Equal([]byte("golden")).Failed())
+		assert.NotContains(t, tb.String(), "golden_test: method called *golden.bufferTB.Fail()")
+	})
+	t.Run("hand-edited", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tool, tb := newTool(s)
+		tool.flag = &[]bool{true}[0]
+		tool.Update([]byte("golden"))
+		assert.NoError(t, s.WriteFile(path, []byte("edited"), 0644))
+
+		tool.flag = nil
+		tool.Read()
+		assert.Contains(t, tb.String(), "golden: the file "+path+" does not match the checksum manifest "+
+			sumFile+", it was edited or corrupted after it was produced by the test TestFoo/sub")
+		assert.Contains(t, tb.String(), "golden_test: method called *golden.bufferTB.Fail()")
+	})
+	t.Run("not-recorded", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, s.WriteFile(path, []byte("golden"), 0644))
+		tool, tb := newTool(s)
+
+		tool.Read()
+		assert.Contains(t, tb.String(), "golden: the file "+path+" is not recorded in the checksum manifest")
+	})
+	t.Run("input-not-verified", func(t *testing.T) {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo", "sub.input"), []byte("input"), 0644))
+		tool, tb := newTool(s)
+
+		assert.Equal(t, "input", string(tool.SetTarget(Input).Read()))
+		assert.NotContains(t, tb.String(), "checksum manifest")
+	})
+	t.Run("input-not-recorded", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tool, _ := newTool(s)
+		tool.flag = &[]bool{true}[0]
+		tool.SetTarget(Input).Update([]byte("input"))
+
+		bs, err := s.ReadFile(sumFile)
+		assert.True(t, os.IsNotExist(err))
+		assert.Empty(t, bs)
+	})
+	t.Run("fallback-verified", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tool, tb := newTool(s)
+		tool = tool.setExtension("json")
+		tool.flag = &[]bool{true}[0]
+		tool.Update([]byte("golden"))
+
+		tool.flag = nil
+		tool = tool.SetPathTemplate(ExtensionLastPathTemplate).SetFallbackPathTemplate(DefaultPathTemplate)
+		assert.False(t, tool.Equal([]byte("golden")).Failed())
+		assert.NotContains(t, tb.String(), "golden_test: method called *golden.bufferTB.Fail()")
+	})
+	t.Run("fallback-migrated", func(t *testing.T) {
+		s := NewMemoryStorage()
+		tool, _ := newTool(s)
+		tool.flag = &[]bool{true}[0]
+		tool = tool.setExtension("json")
+		tool.Update([]byte("golden"))
+		tool.SetPathTemplate(ExtensionLastPathTemplate).
+			SetFallbackPathTemplate(DefaultPathTemplate).
+			Update([]byte("golden"))
+
+		bs, err := s.ReadFile(sumFile)
+		assert.NoError(t, err)
+		assert.Equal(t, "testdata/TestFoo/sub.golden.json "+sumHash([]byte("golden"))+" TestFoo/sub\n", string(bs))
+	})
+}
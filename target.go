@@ -4,28 +4,92 @@
 
 package golden
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
 
-var _ fmt.Stringer = target(0)
+var _ fmt.Stringer = Target(0)
 
 const (
 	// Golden file target.
-	Golden target = iota
+	Golden Target = iota
 	// Input file target.
 	Input
 	// latest the maximum target used. Should not be used in your code.
 	latest
 )
 
-type target uint
+// Target is the kind of the file with test data, the name of the target
+// is the last part of the file name in the default layout, for example
+// "testdata/TestFoo.golden". Custom targets are registered by NewTarget.
+type Target uint
+
+var (
+	// targetsMu protects the targets.
+	targetsMu sync.RWMutex
+	// targets the names of the custom targets, the custom target with the
+	// index i is latest+1+i.
+	targets []string
+)
+
+// NewTarget registers the custom target with the name, so that one test
+// can own several related files, for example "testdata/TestFoo.stderr" for
+// the target registered with the name "stderr". The custom target is used
+// with SetTarget, Read, Update and Equal, the Equal compares the actual
+// value with the file of the custom target instead of the golden file.
+// Registering the same name again returns the same target, the names
+// "golden" and "input" return Golden and Input.
+//
+// Panics if the name is empty or contains characters other than letters,
+// digits, '-' and '_'.
+func NewTarget(name string) Target {
+	if name == "" || strings.TrimLeft(name, targetNameChars) != "" {
+		panic(fmt.Sprintf("golden: invalid target name: %q", name))
+	}
+
+	targetsMu.Lock()
+	defer targetsMu.Unlock()
+
+	for _, t := range []Target{Golden, Input} {
+		if t.String() == name {
+			return t
+		}
+	}
+	for i, n := range targets {
+		if n == name {
+			return latest + 1 + Target(i)
+		}
+	}
+	targets = append(targets, name)
 
-func (t target) String() string {
+	return latest + Target(len(targets))
+}
+
+// targetNameChars the characters allowed in the names of the targets.
+const targetNameChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
+
+func (t Target) String() string {
 	switch t {
 	case Golden:
 		return "golden"
 	case Input:
 		return "input"
-	default:
-		panic(fmt.Sprintf("unsupported target: %d", t))
 	}
+
+	if t > latest {
+		targetsMu.RLock()
+		defer targetsMu.RUnlock()
+		if i := int(t - latest - 1); i < len(targets) {
+			return targets[i]
+		}
+	}
+
+	panic(fmt.Sprintf("unsupported target: %d", t))
+}
+
+// custom reports whether the target is registered by NewTarget.
+func (t Target) custom() bool {
+	return t > latest
 }
@@ -5,6 +5,8 @@
 package golden
 
 import (
+	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,7 +14,7 @@ import (
 
 func Test_target_String(t *testing.T) {
 	tests := []struct {
-		target target
+		target Target
 		want   string
 		runner func(assert.TestingT, assert.PanicTestFunc, ...interface{}) bool
 	}{
@@ -40,3 +42,36 @@ func Test_target_String(t *testing.T) {
 		})
 	}
 }
+
+func TestNewTarget(t *testing.T) {
+	stderr := NewTarget("stderr")
+	assert.True(t, stderr > latest)
+	assert.Equal(t, "stderr", stderr.String())
+	assert.Equal(t, stderr, NewTarget("stderr"), "registering again must return the same target")
+	assert.NotEqual(t, stderr, NewTarget("stdout"))
+	assert.Equal(t, Golden, NewTarget("golden"))
+	assert.Equal(t, Input, NewTarget("input"))
+
+	for _, name := range []string{"", "std err", "a.b", "a/b"} {
+		assert.PanicsWithValue(t, fmt.Sprintf("golden: invalid target name: %q", name), func() {
+			NewTarget(name)
+		})
+	}
+}
+
+func TestTool_SetTarget_custom(t *testing.T) {
+	stderr := NewTarget("stderr")
+	s := NewMemoryStorage()
+	tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s).SetTarget(stderr)
+	tool.flag = &[]bool{true}[0]
+	tool.Assert([]byte("error"))
+
+	bs, err := s.ReadFile(filepath.Join("testdata", "TestFoo.stderr"))
+	assert.NoError(t, err)
+	assert.Equal(t, "error", string(bs))
+
+	tool.flag = nil
+	assert.Equal(t, "error", string(tool.Read()))
+	assert.False(t, tool.Equal([]byte("error")).Failed())
+	assert.True(t, tool.SetTarget(Golden).Equal([]byte("error")).Failed())
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import "encoding/json"
+
+// RunT is a typed functional that automates the process of reading the
+// input file of the test, decoding the input into the value of the type
+// In, the execution of the input function of testing, encoding the result
+// of the type Out and checking the results, for example:
+//
+//	golden.RunT(t, golden.DecodeJSON[Request], handle, golden.EncodeJSON[Response])
+//
+// The test fails if the input cannot be decoded or the result cannot be
+// encoded. The error of the function is handled as in Tool.Run.
+func RunT[In, Out any](
+	t TestingTB,
+	decode func([]byte) (In, error),
+	fn func(In) (Out, error),
+	encode func(Out) ([]byte, error),
+) {
+	if h, ok := t.(testingHelper); ok {
+		h.Helper()
+	}
+	RunToolT(SetTest(t), decode, fn, encode)
+}
+
+// RunToolT is the same as RunT, but uses the configured tool, for example
+// with a prefix or an extension of the golden files.
+func RunToolT[In, Out any](
+	tool Tool,
+	decode func([]byte) (In, error),
+	fn func(In) (Out, error),
+	encode func(Out) ([]byte, error),
+) {
+	if h, ok := tool.test.(testingHelper); ok {
+		h.Helper()
+	}
+	tool.Run(func(input []byte) ([]byte, error) {
+		in, err := decode(input)
+		tool.noError(err)
+
+		out, err := fn(in)
+		if err != nil {
+			return nil, err
+		}
+
+		bs, err := encode(out)
+		tool.noError(err)
+		return bs, nil
+	})
+}
+
+// DecodeJSON decodes the JSON input into the value of the type T, it is
+// intended for use as the decoder of RunT.
+func DecodeJSON[T any](data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// EncodeJSON encodes the value of the type T into the indented JSON with
+// the trailing new line, it is intended for use as the encoder of RunT.
+func EncodeJSON[T any](v T) ([]byte, error) {
+	bs, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(bs, '\n'), nil
+}
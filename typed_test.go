@@ -0,0 +1,69 @@
+// Copyright (c) 2019-2024 Vasiliy Vasilyuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunToolT(t *testing.T) {
+	type request struct {
+		Name string `json:"name"`
+	}
+	type response struct {
+		Greeting string `json:"greeting"`
+	}
+	greet := func(r request) (response, error) {
+		if r.Name == "" {
+			return response{}, errors.New("empty name")
+		}
+		return response{Greeting: "Hello, " + r.Name}, nil
+	}
+	newStorage := func(input string) Storage {
+		s := NewMemoryStorage()
+		assert.NoError(t, s.MkdirAll("testdata", 0755))
+		assert.NoError(t, s.WriteFile(filepath.Join("testdata", "TestFoo.input"), []byte(input), 0644))
+		return s
+	}
+
+	t.Run("update", func(t *testing.T) {
+		s := newStorage(`{"name": "Gopher"}`)
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s)
+		tool.flag = &[]bool{true}[0]
+
+		RunToolT(tool, DecodeJSON[request], greet, EncodeJSON[response])
+		bs, err := s.ReadFile(filepath.Join("testdata", "TestFoo.golden"))
+		assert.NoError(t, err)
+		assert.Equal(t, "{\n\t\"greeting\": \"Hello, Gopher\"\n}\n", string(bs))
+
+		tool = tool.SetTest(&bufferTB{name: "TestFoo"})
+		tool.flag = nil
+		assert.NotPanics(t, func() {
+			RunToolT(tool, DecodeJSON[request], greet, EncodeJSON[response])
+		})
+	})
+	t.Run("decode-error", func(t *testing.T) {
+		tb := &bufferTB{name: "TestFoo"}
+		tool := _golden.SetTest(tb).SetStorage(newStorage(`{`))
+		assert.Panics(t, func() {
+			RunToolT(tool, DecodeJSON[request], greet, EncodeJSON[response])
+		})
+		assert.Contains(t, tb.String(), "unexpected end of JSON input")
+	})
+	t.Run("error-golden", func(t *testing.T) {
+		s := newStorage(`{"name": ""}`)
+		tool := _golden.SetTest(&bufferTB{name: "TestFoo"}).SetStorage(s).SetErrorGolden(true)
+		tool.flag = &[]bool{true}[0]
+
+		RunToolT(tool, DecodeJSON[request], greet, EncodeJSON[response])
+		bs, err := s.ReadFile(filepath.Join("testdata", "TestFoo.err.golden"))
+		assert.NoError(t, err)
+		assert.Equal(t, "empty name", string(bs))
+	})
+}